@@ -0,0 +1,305 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ArgsMode defines how command arguments are split.
+type ArgsMode int
+
+// Args modes.
+const (
+	// ArgsFields splits arguments by spaces.
+	ArgsFields ArgsMode = iota
+	// ArgsQuoted splits arguments like shell does: quotes group words and
+	// backslash escapes the next character.
+	ArgsQuoted
+	// ArgsFlags is like ArgsQuoted but --key=value and --key arguments are put
+	// into Command.Flags. Arguments after -- are never flags.
+	ArgsFlags
+)
+
+var (
+	ErrUnclosedQuote = errors.New("telegram: unclosed quote")
+	ErrMissingArg    = errors.New("telegram: missing argument")
+)
+
+// ArgError describes an invalid command argument or flag. Its message is
+// written for a user. Err is ErrMissingArg, ErrUnclosedQuote or an error of
+// parsing the value.
+type ArgError struct {
+	Name  string // argument name, flag name with "--" or empty for extra args
+	Value string
+	Err   error
+}
+
+// Error implements error interface.
+func (e *ArgError) Error() string {
+	switch {
+	case e.Err == ErrMissingArg:
+		return fmt.Sprintf("missing %s", e.Name)
+	case e.Err == ErrUnclosedQuote:
+		return fmt.Sprintf("unclosed quote in %s", e.Name)
+	case e.Name == "":
+		return fmt.Sprintf("unexpected argument %q", e.Value)
+	}
+	return fmt.Sprintf("invalid %s %q: %s", e.Name, e.Value, e.Err)
+}
+
+// parseArgs splits s into arguments and flags according to mode.
+func parseArgs(s string, mode ArgsMode) (args []string, flags map[string]string, err error) {
+	switch mode {
+	case ArgsQuoted:
+		args, err = ParseArgs(s)
+	case ArgsFlags:
+		if args, err = ParseArgs(s); err == nil {
+			args, flags = splitFlags(args)
+		}
+	default:
+		args = splitArgs(s)
+	}
+	return
+}
+
+// quotes maps opening quotes to closing ones. Typographic quotes are inserted by
+// mobile keyboards instead of ASCII ones. A single quote opens quotes only at
+// the beginning of an argument, so apostrophes like in "don't" are kept.
+var quotes = map[rune]rune{'"': '"', '\'': '\'', '“': '”', '«': '»'}
+
+// ParseArgs splits s into arguments like shell does. Text in quotes is a single
+// argument. Backslash escapes the next character outside of single quotes. A
+// single quote inside a word is an apostrophe.
+// ErrUnclosedQuote is returned for unbalanced quotes.
+func ParseArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		buf     strings.Builder
+		inArg   bool
+		quote   rune // closing quote when inside quotes
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			buf.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				buf.WriteRune(r)
+			}
+		case quotes[r] != 0 && (r != '\'' || !inArg):
+			quote = quotes[r]
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, buf.String())
+				buf.Reset()
+				inArg = false
+			}
+		default:
+			buf.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, ErrUnclosedQuote
+	}
+	// A trailing backslash escapes nothing.
+	if escaped {
+		buf.WriteRune('\\')
+	}
+	if inArg {
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
+
+// splitFlags moves --key=value and --key arguments into flags. A flag without
+// value is "true".
+func splitFlags(args []string) ([]string, map[string]string) {
+	var rest []string
+	flags := map[string]string{}
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "--") || len(arg) == 2 {
+			rest = append(rest, arg)
+			continue
+		}
+		kv := strings.SplitN(arg[2:], "=", 2)
+		if len(kv) == 1 {
+			kv = append(kv, "true")
+		}
+		flags[kv[0]] = kv[1]
+	}
+	return rest, flags
+}
+
+// Bind stores arguments and flags of c in fields of the struct v points to.
+//
+// Fields with `arg:"name"` tag are bound to arguments in order of fields. An
+// argument is required unless the tag has ",optional" suffix. A []string field
+// takes the rest of arguments. Fields with `flag:"name"` tag are bound to flags.
+// Supported field types are string, bool, integers, floats, time.Duration and
+// time.Time parsed with `layout:"..."` tag (time.RFC3339 by default).
+//
+// Bind returns *ArgError if an argument or a flag cannot be bound. Other errors
+// mean that the struct has a tagged field of unsupported type.
+func (c *Command) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic("telegram: Bind requires a pointer to struct")
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	if err := checkFields(rt); err != nil {
+		return err
+	}
+
+	args := c.Args
+	used := map[string]bool{}
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if name, ok := f.Tag.Lookup("flag"); ok {
+			value, ok := c.Flags[name]
+			if !ok {
+				continue
+			}
+			used[name] = true
+			if err := setField(rv.Field(i), value, f.Tag.Get("layout")); err != nil {
+				return &ArgError{Name: "--" + name, Value: value, Err: err}
+			}
+			continue
+		}
+		tag, ok := f.Tag.Lookup("arg")
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(tag, ",optional")
+		if f.Type == reflect.TypeOf([]string(nil)) {
+			rv.Field(i).Set(reflect.ValueOf(args))
+			args = nil
+			continue
+		}
+		if len(args) == 0 {
+			if name == tag {
+				return &ArgError{Name: name, Err: ErrMissingArg}
+			}
+			continue
+		}
+		if err := setField(rv.Field(i), args[0], f.Tag.Get("layout")); err != nil {
+			return &ArgError{Name: name, Value: args[0], Err: err}
+		}
+		args = args[1:]
+	}
+	if len(args) != 0 {
+		return &ArgError{Value: args[0]}
+	}
+	for name, value := range c.Flags {
+		if !used[name] {
+			return &ArgError{Name: "--" + name, Value: value, Err: errors.New("unknown flag")}
+		}
+	}
+	return nil
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// checkFields returns an error if a tagged field of struct type t cannot be
+// bound, so no arguments are parsed into a struct which is bound partly.
+func checkFields(t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		_, isFlag := f.Tag.Lookup("flag")
+		_, isArg := f.Tag.Lookup("arg")
+		switch {
+		case !isFlag && !isArg:
+			continue
+		case isArg && f.Type == reflect.TypeOf([]string(nil)):
+			continue
+		case f.Type == durationType || f.Type == timeType:
+			continue
+		}
+		switch f.Type.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return fmt.Errorf("telegram: unsupported type %s of field %s", f.Type, f.Name)
+		}
+	}
+	return nil
+}
+
+// setField parses s into v according to v's type. Errors are written for a
+// user.
+func setField(v reflect.Value, s string, layout string) error {
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.New("must be a duration like 1h30m")
+		}
+		v.SetInt(int64(d))
+		return nil
+	case timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return fmt.Errorf("must be a time like %s", layout)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a positive integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(f)
+	default:
+		// Unreachable after checkFields.
+		return fmt.Errorf("telegram: unsupported type %s", v.Type())
+	}
+	return nil
+}
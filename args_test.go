@@ -0,0 +1,120 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+)
+
+var ParseArgsTests = []struct {
+	S    string
+	Args []string
+}{
+	{"", nil},
+	{" a  b ", []string{"a", "b"}},
+	{`"team standup" 10:00`, []string{"team standup", "10:00"}},
+	{`'a "b"' c`, []string{`a "b"`, "c"}},
+	{`a\ b \"c\"`, []string{"a b", `"c"`}},
+	{`'a\b'`, []string{`a\b`}},
+	{`""`, []string{""}},
+	{`x"y z"`, []string{"xy z"}},
+	{"“team standup” «now»", []string{"team standup", "now"}},
+	{`a\`, []string{`a\`}},
+	{`don't stop`, []string{"don't", "stop"}},
+	{`'at noon' o'clock`, []string{"at noon", "o'clock"}},
+}
+
+func TestParseArgs(t *testing.T) {
+	for _, tt := range ParseArgsTests {
+		args, err := ParseArgs(tt.S)
+		if err != nil {
+			t.Fatalf("%q: %s", tt.S, err)
+		}
+		if !stringsEqual(args, tt.Args) {
+			t.Errorf("%q: want %q, got %q", tt.S, tt.Args, args)
+		}
+	}
+	if _, err := ParseArgs(`"a b`); err != ErrUnclosedQuote {
+		t.Errorf("error: want %v, got %v", ErrUnclosedQuote, err)
+	}
+}
+
+func TestParseArgsFlags(t *testing.T) {
+	args, flags, err := parseArgs(`--every=1h "a b" --dry -- --c`, ArgsFlags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a b", "--c"}; !stringsEqual(args, want) {
+		t.Errorf("args: want %q, got %q", want, args)
+	}
+	if flags["every"] != "1h" || flags["dry"] != "true" || len(flags) != 2 {
+		t.Errorf("flags: want every=1h, dry=true, got %v", flags)
+	}
+}
+
+type remindArgs struct {
+	What  string        `arg:"what"`
+	At    time.Time     `arg:"time" layout:"15:04"`
+	Count int           `arg:"count,optional"`
+	Every time.Duration `flag:"every"`
+}
+
+func TestCommandBind(t *testing.T) {
+	c := &Command{
+		Args:  []string{"team standup", "10:00"},
+		Flags: map[string]string{"every": "24h"},
+	}
+	var v remindArgs
+	if err := c.Bind(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.What != "team standup" || v.At.Hour() != 10 || v.Count != 0 || v.Every != 24*time.Hour {
+		t.Fatalf("bind: unexpected %+v", v)
+	}
+}
+
+var CommandBindErrorTests = []struct {
+	Command *Command
+	Error   string
+}{
+	{&Command{Args: []string{"a"}}, "missing time"},
+	{&Command{Args: []string{"a", "10"}}, `invalid time "10": must be a time like 15:04`},
+	{&Command{Args: []string{"a", "10:00", "x"}}, `invalid count "x": must be an integer`},
+	{&Command{Args: []string{"a", "10:00", "1", "2"}}, `unexpected argument "2"`},
+	{&Command{Args: []string{"a", "10:00"}, Flags: map[string]string{"every": "1"}}, `invalid --every "1": must be a duration like 1h30m`},
+	{&Command{Args: []string{"a", "10:00"}, Flags: map[string]string{"x": "1"}}, `invalid --x "1": unknown flag`},
+}
+
+func TestCommandBindErrors(t *testing.T) {
+	for _, tt := range CommandBindErrorTests {
+		var v remindArgs
+		err := tt.Command.Bind(&v)
+		if _, ok := err.(*ArgError); !ok {
+			t.Fatalf("%q: want *ArgError, got %#v", tt.Error, err)
+		}
+		if s := err.Error(); s != tt.Error {
+			t.Errorf("error: want %q, got %q", tt.Error, s)
+		}
+	}
+}
+
+func TestCommandBindUnsupportedField(t *testing.T) {
+	var v struct {
+		Name string            `arg:"name"`
+		Tags map[string]string `flag:"tags"`
+	}
+	c := &Command{Args: []string{"a"}}
+	err := c.Bind(&v)
+	if _, ok := err.(*ArgError); err == nil || ok {
+		t.Fatalf("error: want unsupported field, got %#v", err)
+	}
+	if v.Name != "" {
+		t.Fatalf("name: want unbound, got %q", v.Name)
+	}
+}
+
+func TestArgErrorUnclosedQuote(t *testing.T) {
+	e := &ArgError{Name: "arguments", Value: `"a`, Err: ErrUnclosedQuote}
+	if s, want := e.Error(), "unclosed quote in arguments"; s != want {
+		t.Fatalf("error: want %q, got %q", want, s)
+	}
+}
@@ -16,6 +16,8 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"sort"
+	"sync"
 	"time"
 
 	"golang.org/x/net/proxy"
@@ -35,16 +37,20 @@ type Bot interface {
 	Username() string
 	Updates() <-chan *Update
 	Errors() <-chan error
+	Context() context.Context
+	Handle(func(context.Context) error) error
+	Close(context.Context) error
 
 	GetMe(context.Context) (*User, error)
 	GetUpdates(context.Context, ...UpdatesOption) ([]*Update, error)
+	SetWebhook(context.Context, *Webhook) error
 
 	SendMessage(context.Context, *TextMessage) (*Message, error)
 	ForwardMessage(context.Context, *ForwardedMessage) (*Message, error)
-	// SendPhoto(context.Context, *PhotoMessage) (*Message, error)
-	// SendAudio(context.Context, *AudioMessage) (*Message, error)
-	// SendDocument(context.Context, *DocumentMessage) (*Message, error)
-	// SendSticker(context.Context, *StickerMessage) (*Message, error)
+	SendPhoto(context.Context, *PhotoMessage) (*Message, error)
+	SendAudio(context.Context, *AudioMessage) (*Message, error)
+	SendDocument(context.Context, *DocumentMessage) (*Message, error)
+	SendSticker(context.Context, *StickerMessage) (*Message, error)
 	// SendVideo(context.Context, *VideoMessage) (*Message, error)
 	// SendVoice(context.Context, *VoiceMessage) (*Message, error)
 	// SendVoiceNote(context.Context, *VoiceNoteMessage) (*Message, error)
@@ -56,6 +62,10 @@ type Bot interface {
 	EditMessageCaption(context.Context, *MessageCaption) (*Message, error)
 	EditMessageReplyMarkup(context.Context, *MessageReplyMarkup) (*Message, error)
 	DeleteMessage(context.Context, *DeletedMessage) error
+
+	AnswerCallbackQuery(context.Context, *CallbackQueryAnswer) error
+
+	SetMyCommands(context.Context, *MyCommands) error
 }
 
 func NewBot(ctx context.Context, token string, opts ...BotOption) (Bot, error) {
@@ -94,6 +104,8 @@ type botOptions struct {
 	ErrTimeout  time.Duration
 	PollTimeout time.Duration
 	NoUpdates   bool
+	UpdateTypes []UpdateType
+	OffsetStore OffsetStore
 	// TODO: Support several proxies.
 	SOCKS5 *SOCKS5
 }
@@ -136,6 +148,26 @@ func WithoutUpdates() BotOption {
 	}
 }
 
+// WithOffsetStore sets a store used to persist polling offset between
+// restarts.
+func WithOffsetStore(s OffsetStore) BotOption {
+	return func(o *botOptions) {
+		o.OffsetStore = s
+	}
+}
+
+// WithUpdateTypes limits updates the bot receives to the types. No types mean
+// all types, which resets the filter Telegram keeps from a previous run. The
+// filter is used for polling and as a default for SetWebhook.
+func WithUpdateTypes(types ...UpdateType) BotOption {
+	return func(o *botOptions) {
+		if types == nil {
+			types = []UpdateType{}
+		}
+		o.UpdateTypes = types
+	}
+}
+
 type bot struct {
 	username    string
 	url         string
@@ -144,8 +176,25 @@ type bot struct {
 	errTimeout  time.Duration
 	pollTimeout time.Duration
 	noUpdates   bool
+	updateTypes []UpdateType
+	offsets     OffsetStore
 	updatec     chan *Update
 	errorc      chan error
+
+	pollctx    context.Context
+	cancelPoll context.CancelFunc
+	// handlerctx is cancelled when Close returns.
+	handlerctx     context.Context
+	cancelHandlers context.CancelFunc
+	stopOnce       sync.Once
+	stopc          chan struct{} // closed when no more updates must be fetched
+	abortOnce      sync.Once
+	abortc         chan struct{} // closed when updates must not be delivered
+	exitc          chan struct{} // closed when polling goroutine exits
+
+	mu       sync.Mutex
+	handlers int           // number of running handlers
+	idlec    chan struct{} // closed when handlers become 0 if not nil
 }
 
 func newBot(ctx context.Context, token string, opts ...BotOption) *bot {
@@ -153,6 +202,9 @@ func newBot(ctx context.Context, token string, opts ...BotOption) *bot {
 	for _, opt := range opts {
 		opt(o)
 	}
+	if o.OffsetStore == nil {
+		o.OffsetStore = NewMemoryOffsetStore()
+	}
 
 	var client *http.Client
 	if v := o.SOCKS5; v != nil {
@@ -175,12 +227,20 @@ func newBot(ctx context.Context, token string, opts ...BotOption) *bot {
 		errTimeout:  o.ErrTimeout,
 		pollTimeout: o.PollTimeout,
 		noUpdates:   o.NoUpdates,
+		updateTypes: o.UpdateTypes,
+		offsets:     o.OffsetStore,
 		updatec:     make(chan *Update),
 		errorc:      make(chan error),
+		stopc:       make(chan struct{}),
+		abortc:      make(chan struct{}),
+		exitc:       make(chan struct{}),
 	}
+	b.pollctx, b.cancelPoll = context.WithCancel(ctx)
+	b.handlerctx, b.cancelHandlers = context.WithCancel(ctx)
 	if b.noUpdates {
 		close(b.updatec)
 		close(b.errorc)
+		close(b.exitc)
 	}
 	return b
 }
@@ -200,49 +260,184 @@ func (b *bot) getUsername() error {
 }
 
 func (b *bot) listenToUpdates() {
-	var offset int
 	donec := b.ctx.Done()
+	// Polling starts from the first unconfirmed update if offset is not loaded.
+	offset, err := b.offsets.Load()
+	if err != nil {
+		b.sendError(err)
+	}
+	// confirmed is the offset of the last request. Updates before it will not be
+	// received again.
+	confirmed := offset
+	// saved is the offset in the store.
+	saved := offset
 loop:
 	for {
-		u, err := b.GetUpdates(b.ctx, WithOffset(offset), WithTimeout(b.pollTimeout))
+		select {
+		case <-b.stopc:
+			break loop
+		default:
+		}
+
+		opts := []UpdatesOption{WithOffset(offset), WithTimeout(b.pollTimeout)}
+		if b.updateTypes != nil {
+			opts = append(opts, WithAllowedUpdates(b.updateTypes...))
+		}
+		u, err := b.GetUpdates(b.pollctx, opts...)
 		// Handle context errors differently - shutdown gracefully.
 		switch err {
 		case context.Canceled, context.DeadlineExceeded:
 			break loop
 		}
-
 		if err != nil {
-			select {
-			case b.errorc <- err:
-				sleepctx(b.ctx, b.errTimeout)
-				continue
-			case <-donec:
-				break
+			if !b.sendError(err) {
+				break loop
 			}
-		}
-		// No updates this time - repeat the loop and wait for another pack.
-		if len(u) == 0 {
+			sleepctx(b.pollctx, b.errTimeout)
 			continue
 		}
-		// Increment offset according to the last update id. Next time updates
-		// pack will not contain updates up to this last one.
-		offset = u[len(u)-1].UpdateID + 1
+		confirmed = offset
 
+		// The pack is delivered even after Close is called unless Close's context
+		// is done. Undelivered updates are received again after restart.
 		for _, up := range u {
 			select {
 			case b.updatec <- up:
-				continue
 			case <-donec:
 				break loop
+			case <-b.abortc:
+				break loop
+			}
+			// The update is taken by a consumer. Increment offset according to
+			// its id. Next time updates pack will not contain updates up to this
+			// one.
+			offset = up.UpdateID + 1
+		}
+		// The offset is saved once per pack because saving may be slow. Updates
+		// taken before a crash are received again after restart.
+		if offset != saved {
+			if err := b.offsets.Save(offset); err != nil && !b.sendError(err) {
+				break loop
 			}
+			saved = offset
 		}
 	}
 
-	// TODO: How to ensure updatesc and errorc to be drained?
+	// Save the offset of a partly delivered pack.
+	if offset != saved {
+		if err := b.offsets.Save(offset); err != nil {
+			b.trySendError(err)
+		}
+	}
+	if offset != confirmed {
+		b.confirm(offset)
+	}
 
 	// Don't forget to close channels.
 	close(b.updatec)
 	close(b.errorc)
+	close(b.exitc)
+}
+
+// confirm confirms updates up to offset so the API does not send them again.
+// Confirmation requires an extra request which is issued on shutdown.
+func (b *bot) confirm(offset int) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.errTimeout)
+	defer cancel()
+	_, err := b.GetUpdates(ctx, WithOffset(offset), WithLimit(1), WithTimeout(0))
+	if err != nil {
+		b.trySendError(err)
+	}
+}
+
+// trySendError sends err on errorc only if it is received at once, because
+// Errors() may be not read anymore on shutdown.
+func (b *bot) trySendError(err error) {
+	select {
+	case b.errorc <- err:
+	default:
+	}
+}
+
+// Close stops receiving updates and blocks until the polling goroutine exits
+// and running handlers (see Handle) return.
+//
+// An update pack received before Close is still sent on Updates() channel until
+// ctx is done. Then offset of the last consumed update is confirmed and Updates()
+// and Errors() channels are closed. If ctx is done before then, ctx.Err() is
+// returned. Context() is cancelled when Close returns.
+func (b *bot) Close(ctx context.Context) error {
+	b.stopOnce.Do(func() {
+		close(b.stopc)
+		b.cancelPoll()
+	})
+	defer b.cancelHandlers()
+	select {
+	case <-b.exitc:
+		return b.waitHandlers(ctx)
+	case <-ctx.Done():
+	}
+	b.abortOnce.Do(func() {
+		close(b.abortc)
+	})
+	<-b.exitc
+	return ctx.Err()
+}
+
+// Handle runs fn with Context() and registers it as a running handler, so
+// Close waits for fn to return. It returns the error of fn.
+//
+//	err := b.Handle(func(ctx context.Context) error {
+//		err, _ := commands.RunContext(ctx, u)
+//		return err
+//	})
+func (b *bot) Handle(fn func(context.Context) error) error {
+	b.mu.Lock()
+	b.handlers++
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.handlers--
+		if b.handlers == 0 && b.idlec != nil {
+			close(b.idlec)
+			b.idlec = nil
+		}
+		b.mu.Unlock()
+	}()
+	return fn(b.handlerctx)
+}
+
+// waitHandlers blocks until there are no running handlers or ctx is done.
+func (b *bot) waitHandlers(ctx context.Context) error {
+	b.mu.Lock()
+	if b.handlers == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	if b.idlec == nil {
+		b.idlec = make(chan struct{})
+	}
+	idlec := b.idlec
+	b.mu.Unlock()
+	select {
+	case <-idlec:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendError sends err on errorc. It returns false if the bot is stopped before
+// the error is received.
+func (b *bot) sendError(err error) bool {
+	select {
+	case b.errorc <- err:
+		return true
+	case <-b.ctx.Done():
+		return false
+	case <-b.abortc:
+		return false
+	}
 }
 
 // sleepctx pauses for at lease t duration. It returns early if ctx is cancelled or
@@ -259,6 +454,11 @@ func (b *bot) Username() string { return b.username }
 func (b *bot) Updates() <-chan *Update { return b.updatec }
 func (b *bot) Errors() <-chan error    { return b.errorc }
 
+// Context returns a context for update handlers. It is derived from the context
+// passed to NewBot and is cancelled when Close returns, so handlers still
+// running after graceful shutdown are stopped.
+func (b *bot) Context() context.Context { return b.handlerctx }
+
 // call issues HTTP request to API for the method with form values and decodes
 // received data in v. It returns error otherwise.
 func (b *bot) do(ctx context.Context, method string, data interface{}, v interface{}) error {
@@ -270,6 +470,11 @@ func (b *bot) do(ctx context.Context, method string, data interface{}, v interfa
 	}
 	resp, err := post(ctx, b.client, url, contentType, body)
 	if err != nil {
+		// Report a failed file rather than a failed request.
+		var uerr *UploadError
+		if errors.As(err, &uerr) {
+			return uerr
+		}
 		return err
 	}
 	defer resp.Body.Close()
@@ -301,9 +506,15 @@ func (b *bot) do(ctx context.Context, method string, data interface{}, v interfa
 func post(ctx context.Context, client *http.Client, url, bodyType string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequest("POST", url, body)
 	if err != nil {
+		if c, ok := body.(io.Closer); ok {
+			c.Close()
+		}
 		return nil, err
 	}
 	req.Header.Set("Content-Type", bodyType)
+	if s, ok := body.(interface{ Size() int64 }); ok && s.Size() >= 0 {
+		req.ContentLength = s.Size()
+	}
 	return do(ctx, client, req)
 }
 
@@ -336,7 +547,11 @@ func do(ctx context.Context, client *http.Client, req *http.Request) (*http.Resp
 func (b *bot) encode(data interface{}) (io.Reader, string, error) {
 	if m, ok := data.(Multiparter); ok {
 		if v := m.Multipart(); v != nil {
-			return v.Encode()
+			body, contentType, err := v.Encode()
+			if err != nil {
+				return nil, "", err
+			}
+			return body, contentType, nil
 		}
 	}
 	buf := new(bytes.Buffer)
@@ -355,45 +570,193 @@ type Multiparter interface {
 
 type Multipart struct {
 	Form  url.Values
-	Files map[string]InputFile
+	Files map[string]*InputFile
+
+	err error // returned by Encode
 }
 
-// Encode encodes Multipart to multipart/form-data. It returns io.Reader for
-// content, content type with boundary and error. In case of failed encoding
-// io.Reader is nil, content type is an empty string.
-func (m *Multipart) Encode() (io.Reader, string, error) {
-	buf := new(bytes.Buffer)
-	w := multipart.NewWriter(buf)
+// UploadError is returned when a file cannot be read while a request is sent.
+type UploadError struct {
+	Field string // form field of the file
+	Name  string // file name
+	Err   error
+}
+
+// Error implements error interface.
+func (e *UploadError) Error() string {
+	return fmt.Sprintf("telegram: upload %s (%s): %s", e.Field, e.Name, e.Err)
+}
+
+// Unwrap returns the cause of e.
+func (e *UploadError) Unwrap() error {
+	return e.Err
+}
+
+// Encode encodes Multipart to multipart/form-data. It returns the content,
+// content type with boundary and error. In case of failed encoding the content
+// is nil, content type is an empty string.
+//
+// Files are opened by Encode and streamed while the content is read, so the
+// content must be closed if it is not read to the end.
+func (m *Multipart) Encode() (*MultipartBody, string, error) {
+	if m.err != nil {
+		return nil, "", m.err
+	}
+	var files []*openFile
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, key := range sortedFileKeys(m.Files) {
+		file := m.Files[key]
+		rc, size, err := file.open()
+		if err != nil {
+			closeFiles()
+			return nil, "", &UploadError{Field: key, Name: file.Name(), Err: err}
+		}
+		f := &openFile{ReadCloser: rc, r: rc, field: key, file: file, size: size}
+		// Content-Length is sent for a known size, so a growing file must not
+		// be read further.
+		if size >= 0 {
+			f.r = io.LimitReader(rc, size)
+		}
+		files = append(files, f)
+	}
 
-	for key := range m.Form {
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+	size := m.size(files, w.Boundary())
+	go func() {
+		defer closeFiles()
+		pw.CloseWithError(m.write(w, files))
+	}()
+	return &MultipartBody{r: pr, size: size}, w.FormDataContentType(), nil
+}
+
+// write writes the form and files to w.
+func (m *Multipart) write(w *multipart.Writer, files []*openFile) error {
+	for _, key := range sortedFormKeys(m.Form) {
 		if err := w.WriteField(key, m.Form.Get(key)); err != nil {
-			return nil, "", err
+			return err
+		}
+	}
+	for _, f := range files {
+		dest, err := w.CreateFormFile(f.field, f.file.Name())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dest, &fileReader{openFile: f}); err != nil {
+			return err
 		}
 	}
+	return w.Close()
+}
 
-	for key := range m.Files {
-		file := m.Files[key]
-		if dest, err := w.CreateFormFile(key, file.Name()); err != nil {
-			return nil, "", err
-		} else {
-			if _, err := io.Copy(dest, file); err != nil {
-				return nil, "", err
-			}
+// size returns the length of the encoded content or -1 if it is unknown.
+func (m *Multipart) size(files []*openFile, boundary string) int64 {
+	var n int64
+	for _, f := range files {
+		if f.size < 0 {
+			return -1
 		}
+		n += f.size
+	}
+	// Framing does not depend on file contents, so it is written without them.
+	c := &countWriter{}
+	w := multipart.NewWriter(c)
+	w.SetBoundary(boundary)
+	for _, key := range sortedFormKeys(m.Form) {
+		w.WriteField(key, m.Form.Get(key))
+	}
+	for _, f := range files {
+		w.CreateFormFile(f.field, f.file.Name())
 	}
+	w.Close()
+	return n + c.n
+}
 
-	if err := w.Close(); err != nil {
-		return nil, "", err
+type openFile struct {
+	io.ReadCloser
+	r     io.Reader // limited to size if it is known
+	field string
+	file  *InputFile
+	size  int64
+}
+
+// MultipartBody is the content of an encoded multipart form.
+type MultipartBody struct {
+	r    *io.PipeReader
+	size int64
+}
+
+// Read implements io.Reader interface.
+func (b *MultipartBody) Read(p []byte) (int, error) { return b.r.Read(p) }
+
+// Close stops reading files. It implements io.Closer interface.
+func (b *MultipartBody) Close() error { return b.r.Close() }
+
+// Size returns the content length or -1 if the size of any file is unknown.
+func (b *MultipartBody) Size() int64 { return b.size }
+
+// fileReader reads an open file reporting progress. Read errors are returned
+// as *UploadError.
+type fileReader struct {
+	*openFile
+	sent int64
+}
+
+func (r *fileReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		if r.file.progress != nil {
+			r.file.progress(r.sent, r.size)
+		}
 	}
+	// A file shrunk while it is sent does not match Content-Length.
+	if err == io.EOF && r.size >= 0 && r.sent < r.size {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil && err != io.EOF {
+		err = &UploadError{Field: r.field, Name: r.file.Name(), Err: err}
+	}
+	return n, err
+}
+
+type countWriter struct{ n int64 }
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// sortedFormKeys returns keys of v in order.
+func sortedFormKeys(v url.Values) []string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
 
-	return buf, w.FormDataContentType(), nil
+// sortedFileKeys returns keys of files in order.
+func sortedFileKeys(files map[string]*InputFile) []string {
+	keys := make([]string, 0, len(files))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 type updatesOptions struct {
 	Offset  int
 	Limit   int
 	Timeout time.Duration
-	// AllowedUpdates []string
+	// AllowedUpdates is nil when the previous setting must be used.
+	AllowedUpdates []UpdateType
 }
 
 // MarshalJSON implements json.Marshaler interface.
@@ -408,6 +771,9 @@ func (o *updatesOptions) MarshalJSON() ([]byte, error) {
 	if o.Timeout > 0 {
 		m["timeout"] = int(o.Timeout.Seconds())
 	}
+	if o.AllowedUpdates != nil {
+		m["allowed_updates"] = o.AllowedUpdates
+	}
 	return json.Marshal(m)
 }
 
@@ -442,6 +808,17 @@ func WithTimeout(t time.Duration) UpdatesOption {
 	}
 }
 
+// WithAllowedUpdates specifies types of updates to receive. No types mean all
+// types. Without the option the previous setting is used.
+func WithAllowedUpdates(types ...UpdateType) UpdatesOption {
+	return func(o *updatesOptions) {
+		if types == nil {
+			types = []UpdateType{}
+		}
+		o.AllowedUpdates = types
+	}
+}
+
 // Error represents an error returned by API. It satisfies error interface.
 type Error struct {
 	ErrorCode   int
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -79,6 +80,9 @@ var updatesOptionsMarshalJSONTests = []struct {
 	{updatesOptions{Timeout: time.Minute}, `{"timeout":60}`},
 	// Limit
 	{updatesOptions{Limit: 1}, `{"limit":1}`},
+	// AllowedUpdates
+	{updatesOptions{AllowedUpdates: []UpdateType{}}, `{"allowed_updates":[]}`},
+	{updatesOptions{AllowedUpdates: []UpdateType{UpdateMessage, UpdateCallbackQuery}}, `{"allowed_updates":["message","callback_query"]}`},
 }
 
 func TestUpdatesOptions_MarshalJSON(t *testing.T) {
@@ -92,3 +96,186 @@ func TestUpdatesOptions_MarshalJSON(t *testing.T) {
 		}
 	}
 }
+
+var webhookMarshalJSONTests = []struct {
+	Webhook Webhook
+	JSON    string
+}{
+	{Webhook{URL: "u"}, `{"url":"u"}`},
+	{Webhook{URL: "u", AllowedUpdates: []UpdateType{}}, `{"url":"u","allowed_updates":[]}`},
+	{Webhook{URL: "u", AllowedUpdates: []UpdateType{UpdateMessage}}, `{"url":"u","allowed_updates":["message"]}`},
+}
+
+func TestWebhook_MarshalJSON(t *testing.T) {
+	for _, tt := range webhookMarshalJSONTests {
+		b, err := json.Marshal(&tt.Webhook)
+		if err != nil {
+			t.Fatalf("%+v: %s", tt.Webhook, err)
+		}
+		if s := string(b); s != tt.JSON {
+			t.Fatalf("%+v: want %s, got %s", tt.Webhook, tt.JSON, s)
+		}
+	}
+}
+
+func TestBotCloseDeliversPackAndConfirmsOffset(t *testing.T) {
+	offsetc := make(chan int, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var o struct {
+			Offset  int `json:"offset"`
+			Timeout int `json:"timeout"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+			t.Error(err)
+			return
+		}
+		offsetc <- o.Offset
+		var result []*Update
+		switch {
+		case o.Timeout == 0:
+			// Confirmation request.
+		case o.Offset == 0:
+			result = []*Update{{UpdateID: 1}, {UpdateID: 2}}
+		default:
+			<-r.Context().Done()
+			return
+		}
+		json.NewEncoder(w).Encode(&testAPIResponse{
+			Response: apiResponse{OK: true},
+			Result:   result,
+		})
+	}))
+	defer ts.Close()
+
+	b := newBot(context.Background(), "token", withURL(ts.URL+"/"))
+	go b.listenToUpdates()
+
+	if u := <-b.Updates(); u.UpdateID != 1 {
+		t.Fatalf("update: want 1, got %d", u.UpdateID)
+	}
+	errc := make(chan error)
+	go func() { errc <- b.Close(context.Background()) }()
+	// The rest of the pack is delivered after Close.
+	if u := <-b.Updates(); u.UpdateID != 2 {
+		t.Fatalf("update: want 2, got %d", u.UpdateID)
+	}
+	if u, ok := <-b.Updates(); ok {
+		t.Fatalf("update: want closed channel, got %d", u.UpdateID)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Context().Err(); err != context.Canceled {
+		t.Fatalf("context: want %v, got %v", context.Canceled, err)
+	}
+	close(offsetc)
+	var last int
+	for offset := range offsetc {
+		last = offset
+	}
+	if last != 3 {
+		t.Fatalf("confirmed offset: want 3, got %d", last)
+	}
+}
+
+// testRequest is a request received by a test API server.
+type testRequest struct {
+	Method string
+	Body   map[string]interface{}
+}
+
+// newTestBot returns a bot issuing requests to a test server. The server sends
+// requests on the returned channel and responds with result(request).
+func newTestBot(t *testing.T, result func(*testRequest) interface{}) (*bot, <-chan *testRequest, func()) {
+	reqc := make(chan *testRequest, 100)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &testRequest{Method: strings.TrimPrefix(r.URL.Path, "/token/")}
+		if err := json.NewDecoder(r.Body).Decode(&req.Body); err != nil {
+			t.Error(err)
+		}
+		reqc <- req
+		json.NewEncoder(w).Encode(&testAPIResponse{
+			Response: apiResponse{OK: true},
+			Result:   result(req),
+		})
+	}))
+	b := newBot(context.Background(), "token", withURL(ts.URL+"/"), WithoutUpdates())
+	return b, reqc, ts.Close
+}
+
+func TestBotCloseWaitsForHandlers(t *testing.T) {
+	b := newBot(context.Background(), "token", WithoutUpdates())
+	startc, releasec := make(chan struct{}), make(chan struct{})
+	go b.Handle(func(context.Context) error {
+		close(startc)
+		<-releasec
+		return nil
+	})
+	<-startc
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("close: want %v, got %v", context.DeadlineExceeded, err)
+	}
+	if err := b.Context().Err(); err != context.Canceled {
+		t.Fatalf("context: want %v, got %v", context.Canceled, err)
+	}
+
+	close(releasec)
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBotCloseDoesNotBlockOnConfirmError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var o struct {
+			Offset  int `json:"offset"`
+			Timeout int `json:"timeout"`
+		}
+		json.NewDecoder(r.Body).Decode(&o)
+		switch {
+		case o.Timeout == 0:
+			// Confirmation fails.
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case o.Offset != 0:
+			<-r.Context().Done()
+			return
+		}
+		json.NewEncoder(w).Encode(&testAPIResponse{
+			Response: apiResponse{OK: true},
+			Result:   []*Update{{UpdateID: 1}},
+		})
+	}))
+	defer ts.Close()
+
+	b := newBot(context.Background(), "token", withURL(ts.URL+"/"))
+	go b.listenToUpdates()
+	<-b.Updates()
+	// Errors() is not read.
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithUpdateTypesEmpty(t *testing.T) {
+	bodyc := make(chan map[string]interface{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodyc <- body
+		json.NewEncoder(w).Encode(&testAPIResponse{Response: apiResponse{OK: true}, Result: true})
+	}))
+	defer ts.Close()
+
+	b := newBot(context.Background(), "token", withURL(ts.URL+"/"), WithoutUpdates(), WithUpdateTypes())
+	if err := b.SetWebhook(context.Background(), &Webhook{URL: "u"}); err != nil {
+		t.Fatal(err)
+	}
+	types, ok := (<-bodyc)["allowed_updates"].([]interface{})
+	if !ok || len(types) != 0 {
+		t.Fatalf("allowed_updates: want [], got %v", types)
+	}
+}
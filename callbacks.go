@@ -0,0 +1,203 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// https://core.telegram.org/bots/api#inlinekeyboardbutton
+const maxCallbackData = 64
+
+const callbackSeparator = ":"
+
+var (
+	ErrCallbackDataTooLong = errors.New("telegram: callback data is too long")
+	ErrInvalidCallbackData = errors.New("telegram: invalid callback data")
+	ErrCallbackAnswered    = errors.New("telegram: callback query already answered")
+)
+
+// PackCallbackData returns callback data holding prefix and values separated by
+// colons, e.g. "page:2". Values may be strings, bools, ints, int64s and
+// float64s like UnpackCallbackData supports. ErrCallbackDataTooLong is returned
+// if data exceeds 64 bytes.
+func PackCallbackData(prefix string, values ...interface{}) (string, error) {
+	parts := []string{prefix}
+	for _, v := range values {
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = url.QueryEscape(v)
+		case bool:
+			s = strconv.FormatBool(v)
+		case int:
+			s = strconv.Itoa(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case float64:
+			s = strconv.FormatFloat(v, 'g', -1, 64)
+		default:
+			return "", fmt.Errorf("telegram: unsupported callback value %T", v)
+		}
+		parts = append(parts, s)
+	}
+	data := strings.Join(parts, callbackSeparator)
+	if len(data) > maxCallbackData {
+		return "", ErrCallbackDataTooLong
+	}
+	return data, nil
+}
+
+// UnpackCallbackData parses callback data made by PackCallbackData. It returns
+// the prefix and stores values in dst, which are pointers to strings, bools,
+// ints, int64s or float64s. The number of dst must equal the number of values.
+func UnpackCallbackData(data string, dst ...interface{}) (prefix string, err error) {
+	prefix, args := splitCallbackData(data)
+	if err := scanCallbackArgs(args, dst); err != nil {
+		return "", err
+	}
+	return prefix, nil
+}
+
+// splitCallbackData returns the prefix and raw values of data.
+func splitCallbackData(data string) (string, []string) {
+	parts := strings.Split(data, callbackSeparator)
+	return parts[0], parts[1:]
+}
+
+func scanCallbackArgs(args []string, dst []interface{}) error {
+	if len(args) != len(dst) {
+		return ErrInvalidCallbackData
+	}
+	for i, arg := range args {
+		var err error
+		switch v := dst[i].(type) {
+		case *string:
+			*v, err = url.QueryUnescape(arg)
+		case *bool:
+			*v, err = strconv.ParseBool(arg)
+		case *int:
+			*v, err = strconv.Atoi(arg)
+		case *int64:
+			*v, err = strconv.ParseInt(arg, 10, 64)
+		case *float64:
+			*v, err = strconv.ParseFloat(arg, 64)
+		default:
+			panic(fmt.Sprintf("telegram: unsupported callback value %T", dst[i]))
+		}
+		if err != nil {
+			return ErrInvalidCallbackData
+		}
+	}
+	return nil
+}
+
+// Callback represents a callback query matched by Callbacks.
+type Callback struct {
+	Query  *CallbackQuery
+	Update *Update
+	Prefix string
+	Args   []string // raw values after the prefix
+
+	bot      Bot
+	answered int32 // accessed atomically
+}
+
+// Scan stores values of the callback data in dst like UnpackCallbackData.
+func (c *Callback) Scan(dst ...interface{}) error {
+	return scanCallbackArgs(c.Args, dst)
+}
+
+// Answer answers the callback query. a may be nil for an empty answer. It must
+// be called before the handler returns, otherwise Callbacks answers the query
+// itself. ErrCallbackAnswered is returned if the query is already answered.
+func (c *Callback) Answer(ctx context.Context, a *CallbackQueryAnswer) error {
+	if !atomic.CompareAndSwapInt32(&c.answered, 0, 1) {
+		return ErrCallbackAnswered
+	}
+	var v CallbackQueryAnswer
+	if a != nil {
+		v = *a
+	}
+	v.CallbackQueryID = c.Query.ID
+	return c.bot.AnswerCallbackQuery(ctx, &v)
+}
+
+// CallbackFunc represents a function ran on every matching callback query.
+type CallbackFunc func(context.Context, *Callback) error
+
+// Callbacks is the interface of a callback queries register/runner. Handlers are
+// looked for by the prefix of callback data, i.e. data before the first colon.
+type Callbacks interface {
+	Add(prefix string, fn CallbackFunc)
+	Use(mw ...Middleware)
+	Run(*Update) (error, bool)
+	RunContext(context.Context, *Update) (error, bool)
+}
+
+// NewCallbacks returns callbacks answering queries with b.
+func NewCallbacks(b Bot) Callbacks {
+	return &callbacks{bot: b, m: map[string]CallbackFunc{}}
+}
+
+type callbacks struct {
+	bot        Bot
+	m          map[string]CallbackFunc
+	middleware []Middleware
+}
+
+// Add adds the handler for callback queries with the prefix.
+func (c *callbacks) Add(prefix string, fn CallbackFunc) {
+	if strings.Contains(prefix, callbackSeparator) {
+		panic(fmt.Sprintf("telegram: callback prefix %q must not contain %s", prefix, callbackSeparator))
+	}
+	c.m[prefix] = fn
+}
+
+// Use adds middleware wrapping every handler. Middleware receives nil *Command.
+func (c *callbacks) Use(mw ...Middleware) {
+	c.middleware = append(c.middleware, mw...)
+}
+
+// Run executes the handler of u callback query with u.Context(). ok is false
+// when u has no callback query or there is no handler for it.
+func (c *callbacks) Run(u *Update) (error, bool) {
+	return c.RunContext(u.Context(), u)
+}
+
+// RunContext is like Run but the handler receives ctx. The query is answered
+// with ctx after the handler returns or panics unless the handler has answered
+// it.
+func (c *callbacks) RunContext(ctx context.Context, u *Update) (err error, ok bool) {
+	if u == nil || u.CallbackQuery == nil || u.CallbackQuery.Data == nil {
+		return nil, false
+	}
+	q := u.CallbackQuery
+	u = u.WithContext(ctx)
+	prefix, args := splitCallbackData(*q.Data)
+	fn, ok := c.m[prefix]
+	if !ok {
+		return nil, false
+	}
+	cb := &Callback{Query: q, Update: u, Prefix: prefix, Args: args, bot: c.bot}
+	// Middleware may pass values to the handler with the update context.
+	h := func(_ *Command, u *Update) error {
+		cb.Update = u
+		return fn(u.Context(), cb)
+	}
+	defer func() {
+		// Answer the query so the client stops showing progress. A panic of the
+		// handler goes on after that.
+		if atomic.CompareAndSwapInt32(&cb.answered, 0, 1) {
+			a := &CallbackQueryAnswer{CallbackQueryID: q.ID}
+			if aerr := c.bot.AnswerCallbackQuery(ctx, a); err == nil {
+				err = aerr
+			}
+		}
+	}()
+	return chain(c.middleware, h)(nil, u), true
+}
@@ -0,0 +1,127 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPackCallbackData(t *testing.T) {
+	data, err := PackCallbackData("item", "a:b c", 42, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "item:a%3Ab+c:42:true"; data != want {
+		t.Fatalf("data: want %q, got %q", want, data)
+	}
+	var (
+		s string
+		n int
+		b bool
+	)
+	prefix, err := UnpackCallbackData(data, &s, &n, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix != "item" || s != "a:b c" || n != 42 || !b {
+		t.Fatalf("unpack: unexpected (%q, %q, %d, %t)", prefix, s, n, b)
+	}
+	if _, err := UnpackCallbackData(data, &s); err != ErrInvalidCallbackData {
+		t.Fatalf("error: want %v, got %v", ErrInvalidCallbackData, err)
+	}
+	if _, err := PackCallbackData("item", strings.Repeat("a", 60)); err != ErrCallbackDataTooLong {
+		t.Fatalf("error: want %v, got %v", ErrCallbackDataTooLong, err)
+	}
+	if _, err := PackCallbackData("item", uint(1)); err == nil {
+		t.Fatal("error: want unsupported value, got nil")
+	}
+}
+
+func TestCallbacksAnswersQuery(t *testing.T) {
+	b, reqc, closeBot := newTestBot(t, func(*testRequest) interface{} { return true })
+	defer closeBot()
+
+	var page int
+	c := NewCallbacks(b)
+	c.Add("page", func(_ context.Context, cb *Callback) error {
+		return cb.Scan(&page)
+	})
+	alert := &CallbackQueryAnswer{Text: "done", ShowAlert: true}
+	var cb *Callback
+	c.Add("alert", func(ctx context.Context, v *Callback) error {
+		cb = v
+		return cb.Answer(ctx, alert)
+	})
+
+	u := &Update{CallbackQuery: &CallbackQuery{ID: "1", Data: ref("page:2")}}
+	if err, ok := c.Run(u); err != nil || !ok {
+		t.Fatalf("want (nil, true), got (%v, %t)", err, ok)
+	}
+	if page != 2 {
+		t.Fatalf("page: want 2, got %d", page)
+	}
+	if req := <-reqc; req.Method != "answerCallbackQuery" || req.Body["callback_query_id"] != "1" {
+		t.Fatalf("request: want answer to 1, got %+v", req)
+	}
+
+	u = &Update{CallbackQuery: &CallbackQuery{ID: "2", Data: ref("alert")}}
+	if err, ok := c.Run(u); err != nil || !ok {
+		t.Fatalf("want (nil, true), got (%v, %t)", err, ok)
+	}
+	if req := <-reqc; req.Body["text"] != "done" {
+		t.Fatalf("request: want answer with text, got %+v", req)
+	}
+	if len(reqc) != 0 {
+		t.Fatal("query answered twice")
+	}
+	if err := cb.Answer(context.Background(), nil); err != ErrCallbackAnswered {
+		t.Fatalf("error: want %v, got %v", ErrCallbackAnswered, err)
+	}
+	if alert.CallbackQueryID != "" {
+		t.Fatalf("answer: want unchanged, got id %q", alert.CallbackQueryID)
+	}
+
+	u = &Update{CallbackQuery: &CallbackQuery{ID: "3", Data: ref("other:1")}}
+	if _, ok := c.Run(u); ok {
+		t.Fatal("want unhandled update")
+	}
+}
+
+func TestCallbacksAnswersPanickingHandler(t *testing.T) {
+	b, reqc, closeBot := newTestBot(t, func(*testRequest) interface{} { return true })
+	defer closeBot()
+
+	c := NewCallbacks(b)
+	c.Add("panic", func(context.Context, *Callback) error { panic("boom") })
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Fatalf("panic: want boom, got %v", v)
+			}
+		}()
+		c.Run(&Update{CallbackQuery: &CallbackQuery{ID: "1", Data: ref("panic")}})
+	}()
+	if req := <-reqc; req.Body["callback_query_id"] != "1" {
+		t.Fatalf("request: want answer to 1, got %+v", req)
+	}
+}
+
+func TestCallbackAnswerAfterReturn(t *testing.T) {
+	b, reqc, closeBot := newTestBot(t, func(*testRequest) interface{} { return true })
+	defer closeBot()
+
+	var cb *Callback
+	c := NewCallbacks(b)
+	c.Add("late", func(_ context.Context, v *Callback) error {
+		cb = v
+		return nil
+	})
+	c.Run(&Update{CallbackQuery: &CallbackQuery{ID: "1", Data: ref("late")}})
+	<-reqc
+	if err := cb.Answer(context.Background(), nil); err != ErrCallbackAnswered {
+		t.Fatalf("error: want %v, got %v", ErrCallbackAnswered, err)
+	}
+	if len(reqc) != 0 {
+		t.Fatal("query answered twice")
+	}
+}
@@ -1,55 +1,353 @@
 package telegram
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
+	"unicode"
 	"unicode/utf16"
 )
 
 const mentionSign = "@"
 
 // Command represents a command parsed from update's message.
-// Args is a list of words right after the command in the message.
+// Name is the registered name of the command and Alias is the registered alias
+// used in the message if any.
+// Args is a list of words right after the command in the message. RawArgs is
+// the text after the command. Flags are set for commands with ArgsFlags mode.
+// Source is the type of the update holding the message. From is nil for
+// channel posts.
 type Command struct {
-	Name string
-	Args []string
-	From User
-	Chat Chat
-	Date int
+	Name    string
+	Alias   string
+	Args    []string
+	RawArgs string
+	Flags   map[string]string
+	Source  UpdateType
+	From    *User
+	Chat    Chat
+	Date    int
 }
 
 // CommandFunc represents a function ran on every command.
 // The function is ran in a separate goroutine.
 type CommandFunc func(*Command, *Update) error
 
+// CommandContextFunc represents a context-aware function ran on every command.
+// The context is derived from the one passed to RunContext. The command and the
+// update are also available with CommandFromContext and UpdateFromContext.
+type CommandContextFunc func(context.Context, *Command, *Update) error
+
+type contextKey int
+
+const (
+	commandKey contextKey = iota
+	updateKey
+	sessionKey
+)
+
+// CommandFromContext returns the command stored in ctx or nil.
+func CommandFromContext(ctx context.Context) *Command {
+	c, _ := ctx.Value(commandKey).(*Command)
+	return c
+}
+
+// UpdateFromContext returns the update stored in ctx or nil.
+func UpdateFromContext(ctx context.Context) *Update {
+	u, _ := ctx.Value(updateKey).(*Update)
+	return u
+}
+
 // Commands is the interface of a generic commands register/runner.
 type Commands interface {
-	Add(name string, fn CommandFunc)
+	Add(name string, fn CommandFunc, opts ...CommandOption)
+	AddContext(name string, fn CommandContextFunc, opts ...CommandOption)
+	Use(mw ...Middleware)
 	Run(*Update) (error, bool)
+	RunContext(context.Context, *Update) (error, bool)
+	Info(name string) *CommandInfo
+	List() []*CommandInfo
+}
+
+func NewCommands(username string, opts ...CommandsOption) Commands {
+	o := new(commandsOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &commands{
+		username:   username,
+		ignoreCase: o.IgnoreCase,
+		prefixes:   o.Prefixes,
+		m:          map[string]*handler{},
+	}
+}
+
+type commandsOptions struct {
+	IgnoreCase bool
+	Prefixes   []string
+}
+
+type CommandsOption func(*commandsOptions)
+
+// WithIgnoreCase makes commands match regardless of case, e.g. /Start runs
+// /start.
+func WithIgnoreCase() CommandsOption {
+	return func(o *commandsOptions) {
+		o.IgnoreCase = true
+	}
+}
+
+// WithPrefixes adds trigger prefixes, e.g. "!" to run /ban on "!ban" message.
+// Such commands are looked for at the beginning of plain text because they come
+// without bot_command entity.
+func WithPrefixes(prefixes ...string) CommandsOption {
+	return func(o *commandsOptions) {
+		for _, p := range prefixes {
+			// Commands starting with / are parsed from entities.
+			if p != "" && p != "/" {
+				o.Prefixes = append(o.Prefixes, p)
+			}
+		}
+	}
+}
+
+// CommandInfo describes a registered command.
+type CommandInfo struct {
+	Name        string
+	Description string
+	Usage       string // arguments syntax, e.g. "<what> <time>"
+	Examples    []string
+	Aliases     []string
+	Hidden      bool
+}
+
+type commandOptions struct {
+	Timeout  time.Duration
+	ArgsMode ArgsMode
+	Sources  []UpdateType
+	Info     CommandInfo
+}
+
+type CommandOption func(*commandOptions)
+
+// WithDescription sets a short description of the command.
+func WithDescription(s string) CommandOption {
+	return func(o *commandOptions) {
+		o.Info.Description = s
+	}
 }
 
-func NewCommands(username string) Commands {
-	return &commands{username: username, m: map[string]CommandFunc{}}
+// WithUsage sets arguments syntax of the command, e.g. "<what> <time>".
+func WithUsage(s string) CommandOption {
+	return func(o *commandOptions) {
+		o.Info.Usage = s
+	}
+}
+
+// WithExample adds an example of the command call.
+func WithExample(s string) CommandOption {
+	return func(o *commandOptions) {
+		o.Info.Examples = append(o.Info.Examples, s)
+	}
+}
+
+// WithAliases adds other names for the command. Aliases must start with /.
+func WithAliases(names ...string) CommandOption {
+	return func(o *commandOptions) {
+		o.Info.Aliases = append(o.Info.Aliases, names...)
+	}
+}
+
+// Hidden excludes the command from help and BotCommands.
+func Hidden() CommandOption {
+	return func(o *commandOptions) {
+		o.Info.Hidden = true
+	}
+}
+
+// WithArgsMode sets how arguments of the command are split. ArgsFields is used
+// by default.
+func WithArgsMode(m ArgsMode) CommandOption {
+	return func(o *commandOptions) {
+		o.ArgsMode = m
+	}
+}
+
+// WithSources sets types of updates the command is looked for in. Supported
+// types are UpdateMessage (default), UpdateEditedMessage, UpdateChannelPost and
+// UpdateEditedChannelPost.
+func WithSources(types ...UpdateType) CommandOption {
+	return func(o *commandOptions) {
+		o.Sources = types
+	}
+}
+
+// WithCommandTimeout sets a deadline for the handler's context.
+func WithCommandTimeout(t time.Duration) CommandOption {
+	return func(o *commandOptions) {
+		o.Timeout = t
+	}
 }
 
 type commands struct {
-	username string
-	m        map[string]CommandFunc
+	username   string
+	ignoreCase bool
+	prefixes   []string
+
+	m          map[string]*handler // by keys of names and aliases
+	list       []*handler          // in order of adding
+	middleware []Middleware
+}
+
+// handler is a registered command handler.
+type handler struct {
+	Func     CommandContextFunc
+	Timeout  time.Duration
+	ArgsMode ArgsMode
+	Sources  []UpdateType
+	Info     *CommandInfo
+}
+
+// accepts reports whether the command is looked for in updates of type t.
+func (h *handler) accepts(t UpdateType) bool {
+	for _, s := range h.Sources {
+		if s == t {
+			return true
+		}
+	}
+	return false
 }
 
 // Add adds the executor for the command. The executor will be called every time
 // there will be its command in update.
-func (c *commands) Add(name string, fn CommandFunc) {
-	if !strings.HasPrefix(name, "/") {
-		panic(fmt.Sprintf("telegram: command %q must start with /", name))
+func (c *commands) Add(name string, fn CommandFunc, opts ...CommandOption) {
+	c.AddContext(name, func(_ context.Context, cmd *Command, u *Update) error {
+		return fn(cmd, u)
+	}, opts...)
+}
+
+// AddContext adds the context-aware executor for the command. A leading / is
+// added to the name and aliases if they lack it.
+func (c *commands) AddContext(name string, fn CommandContextFunc, opts ...CommandOption) {
+	o := &commandOptions{Sources: []UpdateType{UpdateMessage}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.Info.Name = commandName(name)
+	for i := range o.Info.Aliases {
+		o.Info.Aliases[i] = commandName(o.Info.Aliases[i])
+	}
+	h := &handler{Func: fn, Timeout: o.Timeout, ArgsMode: o.ArgsMode, Sources: o.Sources, Info: &o.Info}
+	c.remove(o.Info.Name)
+	c.unalias(o.Info.Name)
+	c.list = append(c.list, h)
+	c.m[c.key(o.Info.Name)] = h
+	for _, alias := range o.Info.Aliases {
+		c.unalias(alias)
+		c.m[c.key(alias)] = h
+	}
+}
+
+// unalias removes name from aliases of the command owning it, so the name can
+// be taken by another command.
+func (c *commands) unalias(name string) {
+	h, ok := c.m[c.key(name)]
+	if !ok {
+		return
+	}
+	var aliases []string
+	for _, alias := range h.Info.Aliases {
+		if c.key(alias) != c.key(name) {
+			aliases = append(aliases, alias)
+		}
+	}
+	h.Info.Aliases = aliases
+}
+
+// commandName returns name with a leading /.
+func commandName(name string) string {
+	if strings.HasPrefix(name, "/") {
+		return name
+	}
+	return "/" + name
+}
+
+// key returns a key of the command name in the map of handlers.
+func (c *commands) key(name string) string {
+	if c.ignoreCase {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// alias returns the registered alias of h matching name. It returns an empty
+// string if name is the command name.
+func (c *commands) alias(h *handler, name string) string {
+	for _, alias := range h.Info.Aliases {
+		if c.key(alias) == c.key(name) {
+			return alias
+		}
+	}
+	return ""
+}
+
+// remove removes the command with its aliases if it is registered.
+func (c *commands) remove(name string) {
+	for i, h := range c.list {
+		if c.key(h.Info.Name) != c.key(name) {
+			continue
+		}
+		c.list = append(c.list[:i], c.list[i+1:]...)
+		for key, v := range c.m {
+			if v == h {
+				delete(c.m, key)
+			}
+		}
+		return
+	}
+}
+
+// Info returns information about the command with the name or alias. It returns
+// nil for unknown commands.
+func (c *commands) Info(name string) *CommandInfo {
+	if h, ok := c.m[c.key(commandName(name))]; ok {
+		return h.Info
 	}
-	c.m[name] = fn
+	return nil
+}
+
+// List returns information about commands which are not hidden in order of
+// adding.
+func (c *commands) List() []*CommandInfo {
+	var list []*CommandInfo
+	for _, h := range c.list {
+		if !h.Info.Hidden {
+			list = append(list, h.Info)
+		}
+	}
+	return list
+}
+
+// Use adds middleware wrapping every command handler. The first added
+// middleware is the outermost one.
+func (c *commands) Use(mw ...Middleware) {
+	c.middleware = append(c.middleware, mw...)
 }
 
 // Run creates commands from u and executes registered handlers for this commands.
 // ok is false when no command is found in u. Check err whether handlers returned
-// error while execution.
+// error while execution. Handlers receive a context derived from u.Context().
 func (c *commands) Run(u *Update) (err error, ok bool) {
+	return c.RunContext(u.Context(), u)
+}
+
+// RunContext is like Run but handlers receive a context derived from ctx (e.g.
+// Bot.Context()). The context has a deadline if the command has a timeout.
+func (c *commands) RunContext(ctx context.Context, u *Update) (err error, ok bool) {
+	if u != nil {
+		u = u.WithContext(ctx)
+	}
 	if commands := c.parse(u); len(commands) != 0 {
 		err = run(commands)
 		ok = true
@@ -62,15 +360,32 @@ func (c *commands) parse(u *Update) []*command {
 	if u == nil {
 		return nil
 	}
-	if u.Message == nil {
+	// A command may be in a message of any kind. But not in a message of
+	// callback query.
+	if u.CallbackQuery != nil {
+		return nil
+	}
+	m := u.message()
+	if m == nil {
 		return nil
 	}
+	var parsed []*Command
+	for _, e := range m.Entities {
+		if cmd := c.parseCommand(m, e); cmd != nil {
+			parsed = append(parsed, cmd)
+		}
+	}
+	if cmd := c.parsePrefixed(m); cmd != nil {
+		parsed = append(parsed, cmd)
+	}
+	source := u.Type()
 	var cc []*command
-	for _, e := range u.Message.Entities {
-		if cmd := c.parseCommand(u.Message, e); cmd != nil {
-			if fn, ok := c.m[cmd.Name]; ok {
-				cc = append(cc, &command{Func: fn, Command: cmd, Update: u})
-			}
+	for _, cmd := range parsed {
+		cmd.Source = source
+		if h, ok := c.m[c.key(cmd.Name)]; ok && h.accepts(source) {
+			cmd.Name, cmd.Alias = h.Info.Name, c.alias(h, cmd.Name)
+			fn := c.bind(h)
+			cc = append(cc, &command{Func: chain(c.middleware, fn), Command: cmd, Update: u})
 		}
 	}
 	return cc
@@ -79,23 +394,70 @@ func (c *commands) parse(u *Update) []*command {
 // parseCommand parses command from u update according to e message entity.
 // If message entity is not a bot command or the command belongs to another bot
 // then nil is returned.
-//
-// The API documentation garantees that Message.User will not be nil.
-// So the pointer can be safely dereferenced.
 func (c *commands) parseCommand(m *Message, e *MessageEntity) *Command {
 	if !e.IsBotCommand() {
 		return nil
 	}
 	text := *m.Text
 	command := utf16Slice(text, e.Offset, e.Offset+e.Length)
+	tail := utf16Slice(text, e.Offset+e.Length, -1)
+	return c.newCommand(m, command, tail)
+}
+
+// parsePrefixed parses command triggered by one of prefixes at the beginning of
+// m text. If there is no such command then nil is returned.
+func (c *commands) parsePrefixed(m *Message) *Command {
+	if m.Text == nil {
+		return nil
+	}
+	text := *m.Text
+	for _, p := range c.prefixes {
+		if !strings.HasPrefix(text, p) {
+			continue
+		}
+		command, tail := text[len(p):], ""
+		if i := strings.IndexFunc(command, unicode.IsSpace); i != -1 {
+			command, tail = command[:i], command[i:]
+		}
+		if command == "" {
+			return nil
+		}
+		return c.newCommand(m, "/"+command, tail)
+	}
+	return nil
+}
+
+// newCommand returns a command from m with command text (name and optional
+// mention) and tail text after it. Commands to other bots are skipped.
+func (c *commands) newCommand(m *Message, command, tail string) *Command {
 	name, mention := splitCommand(command)
-	// Commands to other bots are skipped.
-	if mention != "" && mention != c.username {
+	if mention != "" && !strings.EqualFold(mention, c.username) {
 		return nil
 	}
-	tail := utf16Slice(text, e.Offset+e.Length, -1)
 	args := splitArgs(tail)
-	return &Command{Name: name, Args: args, From: *m.From, Chat: m.Chat, Date: m.Date}
+	return &Command{Name: name, Args: args, RawArgs: strings.TrimSpace(tail), From: m.From, Chat: m.Chat, Date: m.Date}
+}
+
+// bind returns CommandFunc calling h with a context derived from u.Context().
+// The function returns *ArgError without calling h if arguments cannot be split.
+func (c *commands) bind(h *handler) CommandFunc {
+	return func(cmd *Command, u *Update) error {
+		if h.ArgsMode != ArgsFields {
+			args, flags, err := parseArgs(cmd.RawArgs, h.ArgsMode)
+			if err != nil {
+				return &ArgError{Name: "arguments", Value: cmd.RawArgs, Err: err}
+			}
+			cmd.Args, cmd.Flags = args, flags
+		}
+		ctx := context.WithValue(u.Context(), commandKey, cmd)
+		ctx = context.WithValue(ctx, updateKey, u)
+		if h.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+			defer cancel()
+		}
+		return h.Func(ctx, cmd, u)
+	}
 }
 
 // splitCommand splits command from a message into command name and
@@ -165,6 +527,19 @@ func utf16Slice(s string, from, to int) string {
 	return string(r)
 }
 
+// utf16Len returns the number of UTF-16 codes in s.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
 // multiError contains other errors.
 type multiError struct {
 	errs []error
@@ -1,6 +1,11 @@
 package telegram
 
-import "testing"
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
 
 var UTF16SliceTests = []struct {
 	S        string
@@ -86,3 +91,115 @@ func TestCommandRunRecovers(t *testing.T) {
 		t.Errorf("error: want %q, got %q", "test", s)
 	}
 }
+
+func TestCommandsRunContext(t *testing.T) {
+	c := NewCommands("bot")
+	c.AddContext("/test", func(ctx context.Context, cmd *Command, u *Update) error {
+		if CommandFromContext(ctx) != cmd {
+			t.Error("context: command not found")
+		}
+		if UpdateFromContext(ctx) != u {
+			t.Error("context: update not found")
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("context: want deadline")
+		}
+		return ctx.Err()
+	}, WithCommandTimeout(time.Minute))
+
+	u := &Update{Message: &Message{
+		From:     &User{ID: 1},
+		Text:     ref("/test"),
+		Entities: []*MessageEntity{{Type: "bot_command", Offset: 0, Length: 5}},
+	}}
+	if err, ok := c.RunContext(context.Background(), u); err != nil || !ok {
+		t.Fatalf("want (nil, true), got (%v, %t)", err, ok)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err, _ := c.RunContext(ctx, u); err != context.Canceled {
+		t.Fatalf("error: want %v, got %v", context.Canceled, err)
+	}
+	// Run uses the context of the update.
+	if err, _ := c.Run(u.WithContext(ctx)); err != context.Canceled {
+		t.Fatalf("error: want %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestCommandsIgnoreCaseAndPrefixes(t *testing.T) {
+	var got []string
+	c := NewCommands("Bot", WithIgnoreCase(), WithPrefixes("!"))
+	c.Add("start", func(cmd *Command, _ *Update) error {
+		got = append(got, strings.Join(strings.Fields(cmd.Name+" "+cmd.Alias+" "+cmd.RawArgs), " "))
+		return nil
+	}, WithAliases("Begin"))
+
+	var tests = []struct {
+		Text    string
+		Command bool
+		Want    string
+	}{
+		{"/START", true, "/start"},
+		{"/Start@bot now", true, "/start now"},
+		{"/start@other", true, ""},
+		{"!start now", false, "/start now"},
+		{"!BEGIN@BOT", false, "/start /Begin"},
+		{"!", false, ""},
+		{"start", false, ""},
+	}
+	for _, tt := range tests {
+		got = nil
+		m := &Message{From: &User{ID: 1}, Text: ref(tt.Text)}
+		if tt.Command {
+			n := strings.IndexRune(tt.Text+" ", ' ')
+			m.Entities = []*MessageEntity{{Type: "bot_command", Offset: 0, Length: n}}
+		}
+		_, ok := c.Run(&Update{Message: m})
+		if ok != (tt.Want != "") {
+			t.Fatalf("%q: want handled %t, got %t", tt.Text, tt.Want != "", ok)
+		}
+		if ok && got[0] != tt.Want {
+			t.Fatalf("%q: want %q, got %q", tt.Text, tt.Want, got[0])
+		}
+	}
+}
+
+func TestCommandsSources(t *testing.T) {
+	var got *Command
+	fn := func(cmd *Command, _ *Update) error {
+		got = cmd
+		return nil
+	}
+	c := NewCommands("bot")
+	c.Add("/a", fn)
+	c.Add("/b", fn, WithSources(UpdateEditedMessage, UpdateChannelPost))
+
+	message := func(text string) *Message {
+		return &Message{
+			Text:     ref(text),
+			Entities: []*MessageEntity{{Type: "bot_command", Offset: 0, Length: len(text)}},
+		}
+	}
+	var tests = []struct {
+		Update *Update
+		Source UpdateType
+	}{
+		{&Update{Message: message("/a")}, UpdateMessage},
+		{&Update{EditedMessage: message("/a")}, ""},
+		{&Update{Message: message("/b")}, ""},
+		{&Update{EditedMessage: message("/b")}, UpdateEditedMessage},
+		// Channel posts have no sender.
+		{&Update{ChannelPost: message("/b")}, UpdateChannelPost},
+		{&Update{EditedChannelPost: message("/b")}, ""},
+	}
+	for _, tt := range tests {
+		got = nil
+		_, ok := c.Run(tt.Update)
+		if ok != (tt.Source != "") {
+			t.Fatalf("%s: want handled %t, got %t", tt.Update.Type(), tt.Source != "", ok)
+		}
+		if ok && got.Source != tt.Source {
+			t.Fatalf("source: want %s, got %s", tt.Source, got.Source)
+		}
+	}
+}
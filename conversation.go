@@ -0,0 +1,258 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EndConversation is returned by StepFunc to finish a conversation.
+const EndConversation = ""
+
+// ConversationKey identifies a conversation with a user in a chat.
+type ConversationKey struct {
+	ChatID int64
+	UserID int
+}
+
+// conversationKey returns a key for u. ok is false if u has no chat or sender.
+func conversationKey(u *Update) (key ConversationKey, ok bool) {
+	m, from := u.message(), u.from()
+	if m == nil || from == nil {
+		return key, false
+	}
+	return ConversationKey{ChatID: m.Chat.ID, UserID: from.ID}, true
+}
+
+// State is a state of a conversation. Handlers may keep answers in Data.
+type State struct {
+	Conversation string
+	Name         string
+	Data         map[string]string
+	Expires      time.Time // zero time means no expiration
+}
+
+// StateStore is the interface of a conversation states storage. A user has at
+// most one active conversation in a chat.
+type StateStore interface {
+	// Get returns nil if there is no state for the key.
+	Get(ConversationKey) (*State, error)
+	Set(ConversationKey, *State) error
+	Delete(ConversationKey) error
+}
+
+// NewMemoryStateStore returns a store which keeps states in memory.
+func NewMemoryStateStore() StateStore {
+	return &memoryStateStore{m: map[ConversationKey]*State{}}
+}
+
+type memoryStateStore struct {
+	mu sync.Mutex
+	m  map[ConversationKey]*State
+}
+
+func (s *memoryStateStore) Get(key ConversationKey) (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.m[key]; ok {
+		return v.clone(), nil
+	}
+	return nil, nil
+}
+
+func (s *memoryStateStore) Set(key ConversationKey, v *State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = v.clone()
+	return nil
+}
+
+func (s *memoryStateStore) Delete(key ConversationKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, key)
+	return nil
+}
+
+// clone returns a deep copy of s.
+func (s *State) clone() *State {
+	c := *s
+	c.Data = make(map[string]string, len(s.Data))
+	for k, v := range s.Data {
+		c.Data[k] = v
+	}
+	return &c
+}
+
+// StepFunc represents a function handling an update in a conversation state.
+// It returns the name of the next state or EndConversation.
+type StepFunc func(ctx context.Context, s *State, u *Update) (next string, err error)
+
+// Conversation is the interface of a multi-step dialog runner. A conversation
+// starts with an entry command. Then every update from the user in the chat is
+// handled by the step of the current state until the conversation ends, is
+// cancelled with a cancel command or expires.
+//
+// An active conversation takes priority over other handlers. So it should be
+// mounted to Router before Commands.
+type Conversation interface {
+	Entry(command string, fn StepFunc)
+	Step(state string, fn StepFunc)
+	Cancel(command string, fn StepFunc)
+	Run(*Update) (error, bool)
+	RunContext(context.Context, *Update) (error, bool)
+}
+
+type conversationOptions struct {
+	Timeout time.Duration
+}
+
+type ConversationOption func(*conversationOptions)
+
+// WithConversationTimeout sets how long a conversation waits for the next
+// update. An expired conversation is ended silently.
+func WithConversationTimeout(t time.Duration) ConversationOption {
+	return func(o *conversationOptions) {
+		o.Timeout = t
+	}
+}
+
+// NewConversation returns a conversation with the name keeping states in store.
+// Username is used to parse entry and cancel commands.
+func NewConversation(name, username string, store StateStore, opts ...ConversationOption) Conversation {
+	o := new(conversationOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &conversation{
+		name:    name,
+		store:   store,
+		timeout: o.Timeout,
+		entries: NewCommands(username),
+		cancels: NewCommands(username),
+		steps:   map[string]StepFunc{},
+	}
+}
+
+type conversation struct {
+	name    string
+	store   StateStore
+	timeout time.Duration
+	entries Commands
+	cancels Commands
+	steps   map[string]StepFunc
+}
+
+// Entry adds the command starting the conversation. fn is called with a new
+// state.
+func (c *conversation) Entry(command string, fn StepFunc) {
+	c.entries.AddContext(command, func(ctx context.Context, _ *Command, u *Update) error {
+		return c.step(ctx, fn, &State{Conversation: c.name, Data: map[string]string{}}, u)
+	})
+}
+
+// Step adds the handler of the state.
+func (c *conversation) Step(state string, fn StepFunc) {
+	c.steps[state] = fn
+}
+
+// Cancel adds the command ending an active conversation. fn may be nil. The
+// conversation ends regardless of fn result.
+func (c *conversation) Cancel(command string, fn StepFunc) {
+	c.cancels.AddContext(command, func(ctx context.Context, _ *Command, u *Update) error {
+		s := c.active(u)
+		if s == nil {
+			return nil
+		}
+		var err error
+		if fn != nil {
+			_, err = fn(ctx, s, u)
+		}
+		if key, ok := conversationKey(u); ok {
+			if derr := c.store.Delete(key); err == nil {
+				err = derr
+			}
+		}
+		return err
+	})
+}
+
+// Run handles u if the user has an active conversation or u has an entry
+// command. ok is false otherwise.
+func (c *conversation) Run(u *Update) (error, bool) {
+	return c.RunContext(u.Context(), u)
+}
+
+// RunContext is like Run but handlers receive ctx.
+func (c *conversation) RunContext(ctx context.Context, u *Update) (error, bool) {
+	if u == nil {
+		return nil, false
+	}
+	key, ok := conversationKey(u)
+	if !ok {
+		return nil, false
+	}
+	s, err := c.store.Get(key)
+	if err != nil {
+		return err, true
+	}
+	if s != nil && !s.Expires.IsZero() && time.Now().After(s.Expires) {
+		if err := c.store.Delete(key); err != nil {
+			return err, true
+		}
+		s = nil
+	}
+	if s == nil {
+		return c.entries.RunContext(ctx, u)
+	}
+	// Another conversation is active.
+	if s.Conversation != c.name {
+		return nil, false
+	}
+	if err, ok := c.cancels.RunContext(ctx, u); ok {
+		return err, true
+	}
+	fn, ok := c.steps[s.Name]
+	if !ok {
+		// The state is not known anymore (e.g. after code changes).
+		if err := c.store.Delete(key); err != nil {
+			return err, true
+		}
+		return nil, false
+	}
+	return c.step(ctx, fn, s, u), true
+}
+
+// active returns the active state of the conversation for u or nil.
+func (c *conversation) active(u *Update) *State {
+	key, ok := conversationKey(u)
+	if !ok {
+		return nil
+	}
+	s, err := c.store.Get(key)
+	if err != nil || s == nil || s.Conversation != c.name {
+		return nil
+	}
+	return s
+}
+
+// step runs fn and moves the conversation to the next state. The state is kept
+// if fn returns an error.
+func (c *conversation) step(ctx context.Context, fn StepFunc, s *State, u *Update) error {
+	key, ok := conversationKey(u)
+	if !ok {
+		return nil
+	}
+	next, err := fn(ctx, s, u)
+	if err != nil {
+		return err
+	}
+	if next == EndConversation {
+		return c.store.Delete(key)
+	}
+	s.Name = next
+	if c.timeout > 0 {
+		s.Expires = time.Now().Add(c.timeout)
+	}
+	return c.store.Set(key, s)
+}
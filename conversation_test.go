@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testMessageUpdate(text string) *Update {
+	m := &Message{From: &User{ID: 1}, Chat: Chat{ID: 2}, Text: ref(text)}
+	if text != "" && text[0] == '/' {
+		m.Entities = []*MessageEntity{{Type: "bot_command", Offset: 0, Length: len(text)}}
+	}
+	return &Update{Message: m}
+}
+
+func TestConversation(t *testing.T) {
+	store := NewMemoryStateStore()
+	var done map[string]string
+	c := NewConversation("book", "bot", store)
+	c.Entry("/book", func(context.Context, *State, *Update) (string, error) {
+		return "name", nil
+	})
+	c.Step("name", func(_ context.Context, s *State, u *Update) (string, error) {
+		s.Data["name"] = *u.Message.Text
+		return "date", nil
+	})
+	c.Step("date", func(_ context.Context, s *State, u *Update) (string, error) {
+		s.Data["date"] = *u.Message.Text
+		done = s.Data
+		return EndConversation, nil
+	})
+	c.Cancel("/cancel", nil)
+
+	run := func(text string, handled bool) {
+		if err, ok := c.Run(testMessageUpdate(text)); err != nil || ok != handled {
+			t.Fatalf("%q: want (nil, %t), got (%v, %t)", text, handled, err, ok)
+		}
+	}
+	run("hello", false)
+	run("/book", true)
+	// An active conversation takes any update.
+	run("/help", true)
+	run("tomorrow", true)
+	if done["name"] != "/help" || done["date"] != "tomorrow" {
+		t.Fatalf("data: unexpected %v", done)
+	}
+	run("hello", false)
+
+	run("/book", true)
+	run("/cancel", true)
+	run("hello", false)
+	if s, _ := store.Get(ConversationKey{ChatID: 2, UserID: 1}); s != nil {
+		t.Fatalf("state: want nil, got %+v", s)
+	}
+}
+
+func TestConversationTimeout(t *testing.T) {
+	store := NewMemoryStateStore()
+	c := NewConversation("book", "bot", store, WithConversationTimeout(time.Minute))
+	c.Entry("/book", func(context.Context, *State, *Update) (string, error) {
+		return "name", nil
+	})
+	c.Step("name", func(context.Context, *State, *Update) (string, error) {
+		return EndConversation, nil
+	})
+	if _, ok := c.Run(testMessageUpdate("/book")); !ok {
+		t.Fatal("want handled entry")
+	}
+	key := ConversationKey{ChatID: 2, UserID: 1}
+	s, _ := store.Get(key)
+	s.Expires = time.Now().Add(-time.Second)
+	store.Set(key, s)
+	if _, ok := c.Run(testMessageUpdate("name")); ok {
+		t.Fatal("want expired conversation")
+	}
+}
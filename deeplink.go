@@ -0,0 +1,141 @@
+package telegram
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+)
+
+// https://core.telegram.org/bots/features#deep-linking
+const (
+	maxPayloadLength = 64
+	signatureLength  = 8
+)
+
+var (
+	ErrPayloadTooLong = errors.New("telegram: deep link payload is too long")
+	ErrInvalidPayload = errors.New("telegram: invalid deep link payload")
+)
+
+// DeepLink returns a link opening a private chat with the bot which sends
+// /start with the payload. The payload is a result of EncodePayload or
+// PayloadSigner.Sign. Username may be taken from Bot.Username().
+func DeepLink(username, payload string) (string, error) {
+	return deepLink(username, "start", payload)
+}
+
+// GroupDeepLink returns a link adding the bot to a group which sends /start
+// with the payload.
+func GroupDeepLink(username, payload string) (string, error) {
+	return deepLink(username, "startgroup", payload)
+}
+
+func deepLink(username, param, payload string) (string, error) {
+	if err := checkPayload(payload); err != nil {
+		return "", err
+	}
+	q := url.Values{param: {payload}}
+	return "https://t.me/" + username + "?" + q.Encode(), nil
+}
+
+// checkPayload checks payload length and characters allowed by API:
+// A-Z, a-z, 0-9, _ and -.
+func checkPayload(s string) error {
+	if len(s) > maxPayloadLength {
+		return ErrPayloadTooLong
+	}
+	for _, r := range s {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '_', r == '-':
+		default:
+			return ErrInvalidPayload
+		}
+	}
+	return nil
+}
+
+// EncodePayload encodes data to a deep link payload using URL-safe base64. Data
+// must not be longer than 48 bytes.
+func EncodePayload(data []byte) (string, error) {
+	s := base64.RawURLEncoding.EncodeToString(data)
+	if len(s) > maxPayloadLength {
+		return "", ErrPayloadTooLong
+	}
+	return s, nil
+}
+
+// DecodePayload decodes a payload encoded with EncodePayload.
+func DecodePayload(s string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidPayload
+	}
+	return data, nil
+}
+
+// PayloadSigner signs payloads so users cannot forge them. A signature takes 8
+// bytes, so data must not be longer than 40 bytes.
+type PayloadSigner struct {
+	key []byte
+}
+
+func NewPayloadSigner(key []byte) *PayloadSigner {
+	return &PayloadSigner{key: key}
+}
+
+// Sign returns an encoded payload holding data and its signature.
+func (s *PayloadSigner) Sign(data []byte) (string, error) {
+	return EncodePayload(append(data[:len(data):len(data)], s.sum(data)...))
+}
+
+// Verify decodes the payload and returns its data. It returns ErrInvalidPayload
+// if the signature does not match.
+func (s *PayloadSigner) Verify(payload string) ([]byte, error) {
+	b, err := DecodePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < signatureLength {
+		return nil, ErrInvalidPayload
+	}
+	data, sig := b[:len(b)-signatureLength], b[len(b)-signatureLength:]
+	if !hmac.Equal(sig, s.sum(data)) {
+		return nil, ErrInvalidPayload
+	}
+	return data, nil
+}
+
+// sum returns a truncated HMAC-SHA256 of data.
+func (s *PayloadSigner) sum(data []byte) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write(data)
+	return h.Sum(nil)[:signatureLength]
+}
+
+// StartFunc represents a function ran on /start command with decoded deep link
+// payload. The payload is nil when /start is sent without it.
+type StartFunc func(ctx context.Context, cmd *Command, u *Update, payload []byte) error
+
+// StartCommand returns a /start handler decoding the payload with DecodePayload
+// or with signer if it is not nil. The handler returns ErrInvalidPayload without
+// calling fn if the payload cannot be decoded or verified.
+func StartCommand(fn StartFunc, signer *PayloadSigner) CommandContextFunc {
+	return func(ctx context.Context, cmd *Command, u *Update) error {
+		var payload []byte
+		if len(cmd.Args) != 0 {
+			var err error
+			if signer != nil {
+				payload, err = signer.Verify(cmd.Args[0])
+			} else {
+				payload, err = DecodePayload(cmd.Args[0])
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return fn(ctx, cmd, u, payload)
+	}
+}
@@ -0,0 +1,67 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDeepLink(t *testing.T) {
+	s, err := DeepLink("bot", "ref_1-A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://t.me/bot?start=ref_1-A"; s != want {
+		t.Fatalf("link: want %q, got %q", want, s)
+	}
+	if _, err := DeepLink("bot", "a b"); err != ErrInvalidPayload {
+		t.Fatalf("error: want %v, got %v", ErrInvalidPayload, err)
+	}
+	if _, err := GroupDeepLink("bot", strings.Repeat("a", 65)); err != ErrPayloadTooLong {
+		t.Fatalf("error: want %v, got %v", ErrPayloadTooLong, err)
+	}
+}
+
+func TestPayloadSigner(t *testing.T) {
+	s := NewPayloadSigner([]byte("secret"))
+	data := []byte("user:12345")
+	payload, err := s.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPayload(payload); err != nil {
+		t.Fatalf("payload %q: %s", payload, err)
+	}
+	if b, err := s.Verify(payload); err != nil || !bytes.Equal(b, data) {
+		t.Fatalf("verify: want %q, got (%q, %v)", data, b, err)
+	}
+	forged, _ := EncodePayload([]byte("user:99999xxxxxxxx"))
+	if _, err := s.Verify(forged); err != ErrInvalidPayload {
+		t.Fatalf("forged: want %v, got %v", ErrInvalidPayload, err)
+	}
+	if _, err := NewPayloadSigner([]byte("other")).Verify(payload); err != ErrInvalidPayload {
+		t.Fatalf("other key: want %v, got %v", ErrInvalidPayload, err)
+	}
+	if _, err := s.Sign(make([]byte, 41)); err != ErrPayloadTooLong {
+		t.Fatalf("long data: want %v, got %v", ErrPayloadTooLong, err)
+	}
+}
+
+func TestStartCommand(t *testing.T) {
+	var got []byte
+	fn := StartCommand(func(_ context.Context, _ *Command, _ *Update, payload []byte) error {
+		got = payload
+		return nil
+	}, nil)
+	payload, _ := EncodePayload([]byte("ref"))
+	if err := fn(context.Background(), &Command{Args: []string{payload}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "ref" {
+		t.Fatalf("payload: want %q, got %q", "ref", got)
+	}
+	if err := fn(context.Background(), &Command{}, nil); err != nil || got != nil {
+		t.Fatalf("no payload: want (nil, nil), got (%q, %v)", got, err)
+	}
+}
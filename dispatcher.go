@@ -0,0 +1,264 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+const (
+	defaultWorkers   = 10
+	defaultQueueSize = 100
+)
+
+// UpdateFunc represents a function ran on every update.
+type UpdateFunc func(*Update) error
+
+// KeyFunc returns a key of the update. Updates with the same key are handled
+// sequentially in order of receiving. ok is false if the update may be handled
+// in any order.
+type KeyFunc func(*Update) (key int64, ok bool)
+
+// ChatKey is a KeyFunc ordering updates within a chat.
+func ChatKey(u *Update) (int64, bool) {
+	if m := u.message(); m != nil {
+		return m.Chat.ID, true
+	}
+	return 0, false
+}
+
+// UserKey is a KeyFunc ordering updates from a user.
+func UserKey(u *Update) (int64, bool) {
+	if from := u.from(); from != nil {
+		return int64(from.ID), true
+	}
+	return 0, false
+}
+
+// Dispatcher is the interface of a concurrent updates runner.
+type Dispatcher interface {
+	Run(context.Context, <-chan *Update) error
+	QueueLen() int
+}
+
+type dispatcherOptions struct {
+	Workers   int
+	QueueSize int
+	Key       KeyFunc
+	ErrorFunc func(*Update, error)
+}
+
+type DispatcherOption func(*dispatcherOptions)
+
+// WithWorkers sets the number of updates handled simultaneously.
+func WithWorkers(n int) DispatcherOption {
+	return func(o *dispatcherOptions) {
+		if n < 1 {
+			n = 1
+		}
+		o.Workers = n
+	}
+}
+
+// WithQueueSize sets the number of received but not handled updates. When the
+// queue is full the dispatcher stops reading updates. Running updates are in
+// the queue too, so it is never smaller than the number of workers.
+func WithQueueSize(n int) DispatcherOption {
+	return func(o *dispatcherOptions) {
+		if n < 1 {
+			n = 1
+		}
+		o.QueueSize = n
+	}
+}
+
+// WithKeyFunc sets a function defining updates order. ChatKey is used by
+// default.
+func WithKeyFunc(fn KeyFunc) DispatcherOption {
+	return func(o *dispatcherOptions) {
+		o.Key = fn
+	}
+}
+
+// WithErrorFunc sets a function called for every error returned by the
+// handler. Errors are skipped by default, but panics are logged with the log
+// package.
+func WithErrorFunc(fn func(*Update, error)) DispatcherOption {
+	return func(o *dispatcherOptions) {
+		o.ErrorFunc = fn
+	}
+}
+
+// NewDispatcher returns a dispatcher running fn on a pool of goroutines.
+func NewDispatcher(fn UpdateFunc, opts ...DispatcherOption) Dispatcher {
+	o := &dispatcherOptions{Workers: defaultWorkers, QueueSize: defaultQueueSize, Key: ChatKey}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.QueueSize < o.Workers {
+		o.QueueSize = o.Workers
+	}
+	d := &dispatcher{
+		fn:        fn,
+		key:       o.Key,
+		errorFunc: o.ErrorFunc,
+		workers:   o.Workers,
+		sem:       make(chan struct{}, o.QueueSize),
+		queues:    map[dispatchKey][]*Update{},
+	}
+	d.cond = sync.NewCond(&d.mu)
+	return d
+}
+
+// dispatchKey identifies a queue of updates. Unordered updates get unique keys
+// with non-zero n.
+type dispatchKey struct {
+	id int64
+	n  uint64
+}
+
+type dispatcher struct {
+	fn        UpdateFunc
+	key       KeyFunc
+	errorFunc func(*Update, error)
+	workers   int
+	sem       chan struct{} // holds a value for every queued or running update
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  map[dispatchKey][]*Update // a key is present while its updates run
+	ready   []dispatchKey             // keys waiting for a worker
+	pending int
+	seq     uint64
+	closed  bool
+}
+
+// Run reads updates from updatec and handles them until updatec is closed or
+// ctx is done. Then it waits for queued updates to be handled and returns
+// ctx.Err() or nil. Run must be called once.
+//
+// An update is read only when there is a place for it in the queue. So updates
+// left in updatec are not lost when the bot is closed.
+func (d *dispatcher) Run(ctx context.Context, updatec <-chan *Update) error {
+	var wg sync.WaitGroup
+	wg.Add(d.workers)
+	for i := 0; i < d.workers; i++ {
+		go func() {
+			defer wg.Done()
+			d.work()
+		}()
+	}
+	err := d.read(ctx, updatec)
+
+	d.mu.Lock()
+	d.closed = true
+	d.cond.Broadcast()
+	d.mu.Unlock()
+
+	wg.Wait()
+	return err
+}
+
+// QueueLen returns the number of updates waiting for a worker.
+func (d *dispatcher) QueueLen() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pending
+}
+
+func (d *dispatcher) read(ctx context.Context, updatec <-chan *Update) error {
+	donec := ctx.Done()
+	for {
+		select {
+		case d.sem <- struct{}{}:
+		case <-donec:
+			return ctx.Err()
+		}
+		select {
+		case u, ok := <-updatec:
+			if !ok {
+				<-d.sem
+				return nil
+			}
+			d.push(u)
+		case <-donec:
+			<-d.sem
+			return ctx.Err()
+		}
+	}
+}
+
+// push adds u to the queue of its key.
+func (d *dispatcher) push(u *Update) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var k dispatchKey
+	if id, ok := d.key(u); ok {
+		k.id = id
+	} else {
+		d.seq++
+		k.n = d.seq
+	}
+	q, running := d.queues[k]
+	d.queues[k] = append(q, u)
+	d.pending++
+	if !running {
+		d.ready = append(d.ready, k)
+		d.cond.Signal()
+	}
+}
+
+// work handles updates until the dispatcher is closed and the queue is empty.
+// A worker takes one update of a key at once and puts the key back to the end
+// of ready keys. So a busy chat does not hold a worker forever.
+func (d *dispatcher) work() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for {
+		for len(d.ready) == 0 && !d.closed {
+			d.cond.Wait()
+		}
+		if len(d.ready) == 0 {
+			return
+		}
+		k := d.ready[0]
+		d.ready = d.ready[1:]
+		u := d.queues[k][0]
+		d.queues[k] = d.queues[k][1:]
+		d.pending--
+
+		d.mu.Unlock()
+		d.handle(u)
+		<-d.sem
+		d.mu.Lock()
+
+		if len(d.queues[k]) == 0 {
+			delete(d.queues, k)
+		} else {
+			d.ready = append(d.ready, k)
+			d.cond.Signal()
+		}
+	}
+}
+
+// handle runs the function for u and reports error if any.
+func (d *dispatcher) handle(u *Update) {
+	var err error
+	defer func() {
+		// If fn panics then replace err with a recovered value - it will hold
+		// the real error.
+		if rerr := recover(); rerr != nil {
+			err = fmt.Errorf("%s", rerr)
+			// Don't lose a panic without an error function.
+			if d.errorFunc == nil {
+				log.Printf("telegram: panic handling update %d: %s\n%s", u.UpdateID, rerr, debug.Stack())
+			}
+		}
+		if err != nil && d.errorFunc != nil {
+			d.errorFunc(u, err)
+		}
+	}()
+	err = d.fn(u)
+}
@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatcherKeepsChatOrder(t *testing.T) {
+	const (
+		workers = 3
+		chats   = 5
+		updates = 100
+	)
+	var (
+		mu      sync.Mutex
+		running int
+		maxRun  int
+		last    = map[int64]int{}
+	)
+	fn := func(u *Update) error {
+		mu.Lock()
+		running++
+		if running > maxRun {
+			maxRun = running
+		}
+		id := u.Message.Chat.ID
+		if prev, ok := last[id]; ok && prev > u.UpdateID {
+			t.Errorf("chat %d: update %d handled after %d", id, u.UpdateID, prev)
+		}
+		last[id] = u.UpdateID
+		mu.Unlock()
+
+		time.Sleep(time.Duration(u.UpdateID%3) * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+
+	updatec := make(chan *Update)
+	go func() {
+		for i := 0; i < updates; i++ {
+			updatec <- &Update{UpdateID: i, Message: &Message{Chat: Chat{ID: int64(i % chats)}}}
+		}
+		close(updatec)
+	}()
+
+	d := NewDispatcher(fn, WithWorkers(workers), WithQueueSize(10))
+	if err := d.Run(context.Background(), updatec); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.QueueLen(); n != 0 {
+		t.Fatalf("queue: want 0, got %d", n)
+	}
+	if maxRun > workers {
+		t.Fatalf("workers: want at most %d, got %d", workers, maxRun)
+	}
+	for id := int64(0); id < chats; id++ {
+		if want := updates - chats + int(id); last[id] != want {
+			t.Fatalf("chat %d: last update want %d, got %d", id, want, last[id])
+		}
+	}
+}
+
+func TestDispatcherReportsErrors(t *testing.T) {
+	updatec := make(chan *Update, 1)
+	updatec <- &Update{}
+	close(updatec)
+
+	var got error
+	fn := func(*Update) error { panic("test") }
+	d := NewDispatcher(fn, WithErrorFunc(func(_ *Update, err error) { got = err }))
+	if err := d.Run(context.Background(), updatec); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Error() != "test" {
+		t.Fatalf("error: want %q, got %v", "test", got)
+	}
+}
+
+func TestDispatcherLogsPanics(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	updatec := make(chan *Update, 1)
+	updatec <- &Update{UpdateID: 7}
+	close(updatec)
+	d := NewDispatcher(func(*Update) error { panic("test") })
+	if err := d.Run(context.Background(), updatec); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, "panic handling update 7: test") {
+		t.Fatalf("log: want panic of update 7, got %q", s)
+	}
+}
+
+func TestDispatcherQueueSizeOfWorkers(t *testing.T) {
+	const workers = 4
+	var (
+		mu      sync.Mutex
+		running int
+		allc    = make(chan struct{})
+	)
+	fn := func(*Update) error {
+		mu.Lock()
+		running++
+		if running == workers {
+			close(allc)
+		}
+		mu.Unlock()
+		select {
+		case <-allc:
+		case <-time.After(100 * time.Millisecond):
+		}
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+	unordered := func(*Update) (int64, bool) { return 0, false }
+	d := NewDispatcher(fn, WithWorkers(workers), WithQueueSize(1), WithKeyFunc(unordered))
+
+	updatec := make(chan *Update, workers)
+	for i := 0; i < workers; i++ {
+		updatec <- &Update{UpdateID: i}
+	}
+	close(updatec)
+	if err := d.Run(context.Background(), updatec); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-allc:
+	default:
+		t.Fatalf("running: want %d updates at once", workers)
+	}
+}
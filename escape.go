@@ -0,0 +1,68 @@
+package telegram
+
+import "strings"
+
+// Escaping rules of formatting options.
+// https://core.telegram.org/bots/api#formatting-options
+
+const (
+	markdownV2Special     = "_*[]()~`>#+-=|{}.!\\"
+	markdownV2CodeSpecial = "`\\"
+	markdownV2URLSpecial  = ")\\"
+	markdownSpecial       = "_*`["
+)
+
+// EscapeMarkdownV2 escapes text to be sent with ModeMarkdownV2 outside of
+// entities or inside bold, italic, underline, strikethrough and spoiler ones.
+func EscapeMarkdownV2(text string) string {
+	return escapeChars(text, markdownV2Special)
+}
+
+// EscapeMarkdownV2Code escapes text inside code and pre entities of
+// ModeMarkdownV2.
+func EscapeMarkdownV2Code(text string) string {
+	return escapeChars(text, markdownV2CodeSpecial)
+}
+
+// EscapeMarkdownV2URL escapes the URL part of an inline link, i.e. (...) of
+// [text](url), of ModeMarkdownV2.
+func EscapeMarkdownV2URL(url string) string {
+	return escapeChars(url, markdownV2URLSpecial)
+}
+
+// EscapeMarkdown escapes text to be sent with legacy ModeMarkdown outside of
+// entities. Legacy Markdown cannot escape characters inside entities.
+func EscapeMarkdown(text string) string {
+	return escapeChars(text, markdownSpecial)
+}
+
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+)
+
+// EscapeHTML escapes text to be sent with ModeHTML. It is safe for both text
+// and attribute values.
+func EscapeHTML(text string) string {
+	return htmlEscaper.Replace(text)
+}
+
+// escapeChars prepends each character of text found in chars with a backslash.
+func escapeChars(text, chars string) string {
+	if !strings.ContainsAny(text, chars) {
+		return text
+	}
+	var b strings.Builder
+	b.Grow(len(text) + 8)
+	// Special characters are ASCII, so bytes are compared to keep invalid
+	// UTF-8 sequences intact.
+	for i := 0; i < len(text); i++ {
+		if strings.IndexByte(chars, text[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package telegram
+
+import (
+	"html"
+	"strings"
+	"testing"
+)
+
+var EscapeTests = []struct {
+	Name   string
+	Escape func(string) string
+	Text   string
+	Want   string
+}{
+	{"markdownv2", EscapeMarkdownV2, "1+1=2. _a_ *b* (c) [d] ~e~ `f` > #g | {h} !i \\", `1\+1\=2\. \_a\_ \*b\* \(c\) \[d\] \~e\~ \` + "`f\\`" + ` \> \#g \| \{h\} \!i \\`},
+	{"markdownv2 unicode", EscapeMarkdownV2, "привет, мир!", `привет, мир\!`},
+	{"markdownv2 code", EscapeMarkdownV2Code, "a_b `c` \\d", "a_b \\`c\\` \\\\d"},
+	{"markdownv2 url", EscapeMarkdownV2URL, `http://x.com/(a)\b`, `http://x.com/(a\)\\b`},
+	{"markdown", EscapeMarkdown, "_a_ *b* `c` [d](e) \\", "\\_a\\_ \\*b\\* \\`c\\` \\[d](e) \\"},
+	{"html", EscapeHTML, `<a href="x">&amp;</a>`, `&lt;a href=&quot;x&quot;&gt;&amp;amp;&lt;/a&gt;`},
+}
+
+func TestEscape(t *testing.T) {
+	for _, tt := range EscapeTests {
+		if got := tt.Escape(tt.Text); got != tt.Want {
+			t.Errorf("%s: want %q, got %q", tt.Name, tt.Want, got)
+		}
+	}
+}
+
+// unescapeBackslash reverses escapeChars. It fails if a character from chars is
+// not escaped.
+func unescapeBackslash(t *testing.T, s, chars string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) && strings.IndexByte(chars, s[i+1]) >= 0 {
+			i++
+			c = s[i]
+		} else if strings.IndexByte(chars, c) >= 0 {
+			t.Fatalf("%q: unescaped %q at %d", s, c, i)
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func fuzzEscape(f *testing.F, escape func(string) string, chars string) {
+	for _, tt := range EscapeTests {
+		f.Add(tt.Text)
+	}
+	f.Add("\xff\\_")
+	f.Fuzz(func(t *testing.T, text string) {
+		if got := unescapeBackslash(t, escape(text), chars); got != text {
+			t.Fatalf("round trip: want %q, got %q", text, got)
+		}
+	})
+}
+
+func FuzzEscapeMarkdownV2(f *testing.F) {
+	fuzzEscape(f, EscapeMarkdownV2, markdownV2Special)
+}
+
+func FuzzEscapeMarkdownV2Code(f *testing.F) {
+	fuzzEscape(f, EscapeMarkdownV2Code, markdownV2CodeSpecial)
+}
+
+func FuzzEscapeMarkdownV2URL(f *testing.F) {
+	fuzzEscape(f, EscapeMarkdownV2URL, markdownV2URLSpecial)
+}
+
+func FuzzEscapeMarkdown(f *testing.F) {
+	fuzzEscape(f, EscapeMarkdown, markdownSpecial)
+}
+
+func FuzzEscapeHTML(f *testing.F) {
+	for _, tt := range EscapeTests {
+		f.Add(tt.Text)
+	}
+	f.Fuzz(func(t *testing.T, text string) {
+		s := EscapeHTML(text)
+		if strings.ContainsAny(s, `<>"`) {
+			t.Fatalf("%q: unescaped characters in %q", text, s)
+		}
+		if got := html.UnescapeString(s); got != text {
+			t.Fatalf("round trip: want %q, got %q", text, got)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	tg "github.com/koorgoo/telegram"
 )
@@ -16,26 +17,39 @@ func main() {
 	}
 
 	cmd := tg.NewCommands(bot.Username())
-	cmd.Add("/hello", Hello(bot))
+	cmd.Use(tg.UsageReply(bot, cmd))
+	cmd.AddContext("/hello", Hello(bot),
+		tg.WithCommandTimeout(10*time.Second),
+		tg.WithDescription("Say hello"),
+		tg.WithUsage("[name]"))
+	cmd.AddContext("/help", tg.HelpCommand(bot, cmd),
+		tg.WithDescription("Show commands"),
+		tg.WithUsage("[command]"))
 
-	callCommand := func(u *tg.Update) {
-		if err, _ := cmd.Run(u); err != nil {
-			log.Println("error:", err)
-		}
+	// Bot.Close waits for handlers run by Handle.
+	callCommand := func(u *tg.Update) error {
+		return bot.Handle(func(ctx context.Context) error {
+			err, _ := cmd.RunContext(ctx, u)
+			return err
+		})
 	}
 
-	for update := range bot.Updates() {
-		go callCommand(update)
+	// Updates of a chat are handled one by one, different chats - in parallel.
+	d := tg.NewDispatcher(callCommand, tg.WithErrorFunc(func(_ *tg.Update, err error) {
+		log.Println("error:", err)
+	}))
+	if err := d.Run(context.Background(), bot.Updates()); err != nil {
+		log.Fatal(err)
 	}
 }
 
-func Hello(bot tg.Bot) tg.CommandFunc {
-	return func(c *tg.Command, u *tg.Update) error {
+func Hello(bot tg.Bot) tg.CommandContextFunc {
+	return func(ctx context.Context, c *tg.Command, u *tg.Update) error {
 		name := "user"
 		if len(c.Args) > 0 {
 			name = c.Args[0]
 		}
-		_, err := bot.SendMessage(context.Background(), &tg.TextMessage{
+		_, err := bot.SendMessage(ctx, &tg.TextMessage{
 			ChatID: u.Message.Chat.ID,
 			Text:   fmt.Sprintf("Hello, %s!", name),
 		})
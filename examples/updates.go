@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"os"
+	"os/signal"
 	"sync"
 	"time"
 
@@ -34,5 +35,18 @@ func main() {
 		}
 	}(wg.Done, b.Errors())
 
+	// Stop receiving updates on interrupt. Updates() and Errors() channels are
+	// closed after the received updates are consumed.
+	go func() {
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, os.Interrupt)
+		<-sigc
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := b.Close(ctx); err != nil {
+			log.Println("close:", err)
+		}
+	}()
+
 	wg.Wait()
 }
@@ -0,0 +1,160 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// InputFile is a file to send. It is either uploaded or referenced by a URL or
+// an ID of a file stored on Telegram servers. Messages with uploads are sent
+// as multipart forms, others as JSON.
+// https://core.telegram.org/bots/api#sending-files
+type InputFile struct {
+	name     string
+	open     func() (io.ReadCloser, int64, error) // nil for references
+	ref      string                               // URL or file ID
+	progress ProgressFunc
+}
+
+// ProgressFunc is called while a file is uploaded. total is -1 if the size of
+// the file is unknown.
+type ProgressFunc func(sent, total int64)
+
+// FileFromPath returns a file uploaded from path. The file is opened when a
+// message is sent.
+func FileFromPath(path string) *InputFile {
+	return &InputFile{
+		name: filepath.Base(path),
+		open: func() (io.ReadCloser, int64, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, 0, err
+			}
+			return f, readerSize(f), nil
+		},
+	}
+}
+
+// FileFromBytes returns a file with the name uploaded from b.
+func FileFromBytes(name string, b []byte) *InputFile {
+	return &InputFile{
+		name: name,
+		open: func() (io.ReadCloser, int64, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), int64(len(b)), nil
+		},
+	}
+}
+
+// FileFromReader returns a file with the name uploaded from r. The file can be
+// sent only once. r is closed after sending if it is an io.Closer.
+//
+// The size of r is known for readers like *bytes.Reader and *os.File, so the
+// request has Content-Length. Otherwise the request is chunked.
+func FileFromReader(name string, r io.Reader) *InputFile {
+	return &InputFile{
+		name: name,
+		open: func() (io.ReadCloser, int64, error) {
+			if rc, ok := r.(io.ReadCloser); ok {
+				return rc, readerSize(r), nil
+			}
+			return ioutil.NopCloser(r), readerSize(r), nil
+		},
+	}
+}
+
+// FileURL returns a file Telegram downloads from url.
+func FileURL(url string) *InputFile {
+	return &InputFile{ref: url}
+}
+
+// FileID returns a file stored on Telegram servers, e.g. PhotoSize.FileID of a
+// received photo.
+func FileID(id string) *InputFile {
+	return &InputFile{ref: id}
+}
+
+// readerSize returns the number of bytes left in r or -1 if it is unknown.
+func readerSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		fi, err := r.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return -1
+		}
+		off, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return fi.Size() - off
+	}
+	return -1
+}
+
+// WithProgress makes fn called while f is uploaded. It returns f.
+func (f *InputFile) WithProgress(fn ProgressFunc) *InputFile {
+	f.progress = fn
+	return f
+}
+
+// Name returns the file name of an upload.
+func (f *InputFile) Name() string {
+	return f.name
+}
+
+// IsUpload reports whether f is uploaded rather than referenced.
+func (f *InputFile) IsUpload() bool {
+	return f != nil && f.open != nil
+}
+
+// MarshalJSON implements json.Marshaler interface. A reference is marshalled as
+// a string and an upload as null, because uploads are sent in multipart forms.
+func (f *InputFile) MarshalJSON() ([]byte, error) {
+	if f.IsUpload() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f.ref)
+}
+
+// newMultipart returns a multipart form of message m with files. Other fields
+// of m are taken from its JSON encoding. It returns nil if there are no
+// uploads among files, so m is sent as JSON. An encoding error is returned by
+// Multipart.Encode.
+func newMultipart(m interface{}, files map[string]*InputFile) *Multipart {
+	uploads := map[string]*InputFile{}
+	for k, f := range files {
+		if f.IsUpload() {
+			uploads[k] = f
+		}
+	}
+	if len(uploads) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return &Multipart{err: err}
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return &Multipart{err: err}
+	}
+	form := url.Values{}
+	for k, v := range fields {
+		if _, ok := uploads[k]; ok || string(v) == "null" {
+			continue
+		}
+		// Strings are sent as is, other values as JSON.
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			s = string(v)
+		}
+		form.Set(k, s)
+	}
+	return &Multipart{Form: form, Files: uploads}
+}
@@ -0,0 +1,217 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInputFileReference(t *testing.T) {
+	b := newBot(context.Background(), "token", WithoutUpdates())
+	for _, f := range []*InputFile{FileID("AgADBAAD"), FileURL("https://example.com/a.png")} {
+		m := &PhotoMessage{ChatID: 1, Photo: f}
+		r, contentType, err := b.encode(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType != jsonContentType {
+			t.Fatalf("content type: want JSON, got %s", contentType)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body["photo"] != f.ref {
+			t.Errorf("photo: want %q, got %v", f.ref, body["photo"])
+		}
+	}
+}
+
+func TestInputFileUpload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	if err := ioutil.WriteFile(path, []byte("path"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := newBot(context.Background(), "token", WithoutUpdates())
+	tests := []struct {
+		Name    string
+		File    *InputFile
+		Content string
+	}{
+		{"song.mp3", FileFromPath(path), "path"},
+		{"a.mp3", FileFromBytes("a.mp3", []byte("bytes")), "bytes"},
+		{"b.mp3", FileFromReader("b.mp3", strings.NewReader("reader")), "reader"},
+	}
+	for _, tt := range tests {
+		m := &AudioMessage{ChatID: 1, Audio: tt.File, Title: "Title", ReplyMarkup: &ReplyKeyboardRemove{RemoveKeyboard: true}}
+		r, contentType, err := b.encode(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		form, err := multipart.NewReader(r, params["boundary"]).ReadForm(1 << 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{"chat_id": "1", "title": "Title", "reply_markup": `{"remove_keyboard":true}`}
+		if len(form.Value) != len(want) {
+			t.Errorf("%s: unexpected fields %v", tt.Name, form.Value)
+		}
+		for k, v := range want {
+			if got := form.Value[k]; len(got) != 1 || got[0] != v {
+				t.Errorf("%s: %s: want %q, got %q", tt.Name, k, v, got)
+			}
+		}
+		fh := form.File["audio"]
+		if len(fh) != 1 || fh[0].Filename != tt.Name {
+			t.Fatalf("%s: unexpected file %v", tt.Name, fh)
+		}
+		f, _ := fh[0].Open()
+		content, _ := ioutil.ReadAll(f)
+		if string(content) != tt.Content {
+			t.Errorf("%s: want content %q, got %q", tt.Name, tt.Content, content)
+		}
+	}
+}
+
+// failingMarkup is a markup which cannot be encoded.
+type failingMarkup struct{}
+
+func (failingMarkup) markup() {}
+
+func (failingMarkup) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("failed")
+}
+
+func TestMultipartEncodingError(t *testing.T) {
+	b := newBot(context.Background(), "token", WithoutUpdates())
+	m := &AudioMessage{ChatID: 1, Audio: FileFromBytes("a.mp3", nil), ReplyMarkup: failingMarkup{}}
+	if _, _, err := b.encode(m); err == nil {
+		t.Fatal("error: want encoding error, got nil")
+	}
+}
+
+func TestMultipartContentLength(t *testing.T) {
+	var sent, total int64
+	m := &DocumentMessage{
+		ChatID: 1,
+		Document: FileFromBytes("a.txt", []byte(strings.Repeat("a", 100000))).WithProgress(func(s, t int64) {
+			sent, total = s, t
+		}),
+	}
+	r, _, err := m.Multipart().Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := r.Size()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(b)) != size {
+		t.Errorf("size: want %d, got %d", len(b), size)
+	}
+	if sent != 100000 || total != 100000 {
+		t.Errorf("progress: want 100000/100000, got %d/%d", sent, total)
+	}
+
+	m.Document = FileFromReader("b.txt", io.MultiReader(strings.NewReader("b")))
+	r, _, _ = m.Multipart().Encode()
+	if size := r.Size(); size != -1 {
+		t.Errorf("size of unknown reader: want -1, got %d", size)
+	}
+	r.Close()
+}
+
+func TestMultipartGrowingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	if err := ioutil.WriteFile(path, []byte("abc"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := &DocumentMessage{ChatID: 1, Document: FileFromPath(path)}
+	r, _, err := m.Multipart().Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	// The file grows after it is opened.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("def")
+	f.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(b)) != r.Size() {
+		t.Errorf("size: want %d, got %d", r.Size(), len(b))
+	}
+}
+
+type failingReader struct{ n int }
+
+var errRead = errors.New("disk failed")
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, errRead
+	}
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+	for i := range p {
+		p[i] = 'a'
+	}
+	r.n -= len(p)
+	return len(p), nil
+}
+
+func TestSendDocumentUpload(t *testing.T) {
+	var lengths []int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			return
+		}
+		lengths = append(lengths, r.ContentLength)
+		json.NewEncoder(w).Encode(&testAPIResponse{Response: apiResponse{OK: true}, Result: &Message{}})
+	}))
+	defer ts.Close()
+	b := newBot(context.Background(), "token", withURL(ts.URL+"/"), WithoutUpdates())
+
+	m := &DocumentMessage{ChatID: 1, Document: FileFromBytes("a.txt", []byte("abc"))}
+	if _, err := b.SendDocument(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if len(lengths) != 1 || lengths[0] <= 0 {
+		t.Errorf("want Content-Length, got %v", lengths)
+	}
+
+	m.Document = FileFromReader("b.txt", &failingReader{n: 1 << 16})
+	_, err := b.SendDocument(context.Background(), m)
+	uerr, ok := err.(*UploadError)
+	if !ok || uerr.Field != "document" || uerr.Name != "b.txt" || uerr.Err != errRead {
+		t.Fatalf("want *UploadError of b.txt, got %#v", err)
+	}
+
+	m.Document = FileFromPath("/nonexistent/c.txt")
+	if _, err := b.SendDocument(context.Background(), m); !errors.As(err, &uerr) || !os.IsNotExist(uerr.Err) {
+		t.Fatalf("want *UploadError of a missing file, got %v", err)
+	}
+}
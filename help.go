@@ -0,0 +1,123 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrUsage may be returned by a command handler when the command is called with
+// wrong arguments. UsageReply middleware replies with the command usage then.
+var ErrUsage = errors.New("telegram: invalid usage")
+
+// Help returns a list of commands which are not hidden with their usage and
+// description. A line per command.
+func Help(c Commands) string {
+	var lines []string
+	for _, info := range c.List() {
+		line := usageLine(info)
+		if info.Description != "" {
+			line += " - " + info.Description
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CommandHelp returns details about the command: usage, description, aliases
+// and examples.
+func CommandHelp(info *CommandInfo) string {
+	lines := []string{usageLine(info)}
+	if info.Description != "" {
+		lines = append(lines, info.Description)
+	}
+	if len(info.Aliases) != 0 {
+		lines = append(lines, "Aliases: "+strings.Join(info.Aliases, ", "))
+	}
+	if len(info.Examples) != 0 {
+		lines = append(lines, "Examples:")
+		lines = append(lines, info.Examples...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// usageLine returns the command name with arguments syntax.
+func usageLine(info *CommandInfo) string {
+	if info.Usage == "" {
+		return info.Name
+	}
+	return info.Name + " " + info.Usage
+}
+
+// HelpCommand returns a handler replying with Help for /help and with
+// CommandHelp for /help <command>.
+func HelpCommand(b Bot, c Commands) CommandContextFunc {
+	return func(ctx context.Context, cmd *Command, u *Update) error {
+		text := Help(c)
+		if len(cmd.Args) != 0 {
+			name := cmd.Args[0]
+			if !strings.HasPrefix(name, "/") {
+				name = "/" + name
+			}
+			if info := c.Info(name); info != nil && !info.Hidden {
+				text = CommandHelp(info)
+			} else {
+				text = "Unknown command " + name
+			}
+		}
+		_, err := b.SendMessage(ctx, &TextMessage{ChatID: cmd.Chat.ID, Text: text})
+		return err
+	}
+}
+
+// UsageReply returns a middleware which replies with usage of the command when
+// its handler returns ErrUsage or *ArgError. The error is replaced with a
+// reply's error then. The reply is sent with u.Context().
+func UsageReply(b Bot, c Commands) Middleware {
+	return func(next CommandFunc) CommandFunc {
+		return func(cmd *Command, u *Update) error {
+			err := next(cmd, u)
+			if cmd == nil || err == nil {
+				return err
+			}
+			var lines []string
+			var argErr *ArgError
+			switch {
+			case errors.As(err, &argErr):
+				lines = append(lines, argErr.Error())
+			case errors.Is(err, ErrUsage):
+			default:
+				return err
+			}
+			// The command may be unknown to c, e.g. if it is added to other
+			// Commands. Its name is the usage then.
+			usage := cmd.Name
+			if info := c.Info(cmd.Name); info != nil {
+				usage = usageLine(info)
+			}
+			lines = append(lines, "Usage: "+usage)
+			m := &TextMessage{ChatID: cmd.Chat.ID, Text: strings.Join(lines, "\n")}
+			if um := u.message(); um != nil {
+				m.ReplyToMessageID = um.MessageID
+			}
+			_, err = b.SendMessage(u.Context(), m)
+			return err
+		}
+	}
+}
+
+// BotCommands returns commands which are not hidden for SetMyCommands. Commands
+// without description are skipped because API requires it.
+func BotCommands(c Commands) []*BotCommand {
+	var list []*BotCommand
+	for _, info := range c.List() {
+		if info.Description == "" {
+			continue
+		}
+		list = append(list, &BotCommand{
+			Command:     strings.TrimPrefix(info.Name, "/"),
+			Description: info.Description,
+		})
+	}
+	return list
+}
@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func testHelpCommands() Commands {
+	c := NewCommands("bot")
+	nop := func(*Command, *Update) error { return nil }
+	c.Add("/remind", nop,
+		WithDescription("Remind about something"),
+		WithUsage("<what> <time>"),
+		WithExample(`/remind "team standup" 10:00`),
+		WithAliases("/r"))
+	c.Add("/debug", nop, Hidden())
+	c.Add("/start", nop)
+	return c
+}
+
+func TestHelp(t *testing.T) {
+	c := testHelpCommands()
+	want := "/remind <what> <time> - Remind about something\n/start"
+	if s := Help(c); s != want {
+		t.Fatalf("help: want %q, got %q", want, s)
+	}
+	want = "/remind <what> <time>\nRemind about something\nAliases: /r\n" +
+		"Examples:\n/remind \"team standup\" 10:00"
+	if s := CommandHelp(c.Info("/r")); s != want {
+		t.Fatalf("command help: want %q, got %q", want, s)
+	}
+}
+
+func TestBotCommands(t *testing.T) {
+	list := BotCommands(testHelpCommands())
+	if len(list) != 1 {
+		t.Fatalf("commands: want 1, got %d", len(list))
+	}
+	if c := list[0]; c.Command != "remind" || c.Description != "Remind about something" {
+		t.Fatalf("command: unexpected %+v", c)
+	}
+}
+
+func TestCommandsRunAlias(t *testing.T) {
+	c := NewCommands("bot")
+	var got *Command
+	c.Add("/remind", func(cmd *Command, _ *Update) error {
+		got = cmd
+		return nil
+	}, WithAliases("/r"))
+	u := &Update{Message: &Message{
+		From:     &User{ID: 1},
+		Text:     ref("/r x"),
+		Entities: []*MessageEntity{{Type: "bot_command", Offset: 0, Length: 2}},
+	}}
+	if _, ok := c.Run(u); !ok {
+		t.Fatal("want handled update")
+	}
+	if got.Name != "/remind" || got.Alias != "/r" {
+		t.Fatalf("command: want /remind via /r, got %s via %s", got.Name, got.Alias)
+	}
+}
+
+func TestUsageReply(t *testing.T) {
+	b, reqc, done := newTestBot(t, func(*testRequest) interface{} { return &Message{} })
+	defer done()
+	c := NewCommands("bot")
+	c.Use(UsageReply(b, c))
+	c.Add("/remind", func(*Command, *Update) error { return ErrUsage }, WithUsage("<what>"))
+	u := &Update{Message: &Message{
+		MessageID: 7,
+		Chat:      Chat{ID: 1},
+		Text:      ref("/remind"),
+		Entities:  []*MessageEntity{{Type: "bot_command", Offset: 0, Length: 7}},
+	}}
+
+	if err, _ := c.Run(u); err != nil {
+		t.Fatal(err)
+	}
+	req := <-reqc
+	if text := req.Body["text"]; text != "Usage: /remind <what>" {
+		t.Fatalf("text: want %q, got %q", "Usage: /remind <what>", text)
+	}
+
+	// The reply is sent with the context of the handler.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err, _ := c.RunContext(ctx, u); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error: want %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestUsageReplyUnknownCommand(t *testing.T) {
+	b, reqc, done := newTestBot(t, func(*testRequest) interface{} { return &Message{} })
+	defer done()
+	// The command is not added to the commands of the middleware.
+	c := NewCommands("bot")
+	c.Use(UsageReply(b, NewCommands("bot")))
+	c.Add("/remind", func(*Command, *Update) error { return ErrUsage })
+
+	if err, _ := c.Run(testMessageUpdate("/remind")); err != nil {
+		t.Fatal(err)
+	}
+	if text := (<-reqc).Body["text"]; text != "Usage: /remind" {
+		t.Fatalf("text: want %q, got %q", "Usage: /remind", text)
+	}
+}
+
+func TestSetMyCommandsNotSet(t *testing.T) {
+	b, _, done := newTestBot(t, func(*testRequest) interface{} { return false })
+	defer done()
+	err := b.SetMyCommands(context.Background(), &MyCommands{Commands: BotCommands(testHelpCommands())})
+	if err != ErrCommandsNotSet {
+		t.Fatalf("error: want %v, got %v", ErrCommandsNotSet, err)
+	}
+}
+
+func TestCommandsOverwriteAlias(t *testing.T) {
+	c := NewCommands("bot")
+	nop := func(*Command, *Update) error { return nil }
+	c.Add("/remind", nop, WithDescription("Remind"), WithAliases("/r", "/rem"))
+	c.Add("/r", nop, WithDescription("Repeat"))
+
+	if aliases := c.Info("/remind").Aliases; !stringsEqual(aliases, []string{"/rem"}) {
+		t.Fatalf("aliases: want [/rem], got %v", aliases)
+	}
+	if info := c.Info("/r"); info.Name != "/r" {
+		t.Fatalf("command: want /r, got %s", info.Name)
+	}
+	want := "/remind - Remind\n/r - Repeat"
+	if s := Help(c); s != want {
+		t.Fatalf("help: want %q, got %q", want, s)
+	}
+}
@@ -0,0 +1,262 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Keyboard limits.
+const (
+	maxInlineRowButtons = 8
+	maxInlineButtons    = 100
+	maxReplyRowButtons  = 12
+	maxReplyButtons     = 300
+)
+
+// Errors of keyboard validation. They are wrapped in *ButtonError.
+var (
+	ErrTooManyButtons = errors.New("telegram: too many buttons")
+	ErrEmptyButton    = errors.New("telegram: button has no text")
+	ErrButtonAction   = errors.New("telegram: inline button must have exactly one action")
+)
+
+// ButtonError describes an invalid button of a keyboard.
+type ButtonError struct {
+	Row, Column int // zero-based
+	Err         error
+}
+
+// Error implements error interface.
+func (e *ButtonError) Error() string {
+	return fmt.Sprintf("%s (row %d, column %d)", e.Err, e.Row, e.Column)
+}
+
+// Unwrap returns the cause of e.
+func (e *ButtonError) Unwrap() error {
+	return e.Err
+}
+
+// CallbackButton returns an inline button sending a callback query with data,
+// e.g. packed with PackCallbackData.
+func CallbackButton(text, data string) *InlineKeyboardButton {
+	return &InlineKeyboardButton{Text: text, CallbackData: data}
+}
+
+// URLButton returns an inline button opening url.
+func URLButton(text, url string) *InlineKeyboardButton {
+	return &InlineKeyboardButton{Text: text, URL: url}
+}
+
+// SwitchInlineButton returns an inline button which prompts a user to select
+// a chat and inserts the bot username and query into the input field. query
+// may be empty.
+func SwitchInlineButton(text, query string) *InlineKeyboardButton {
+	return &InlineKeyboardButton{Text: text, SwitchInlineQuery: &query}
+}
+
+// SwitchInlineCurrentChatButton returns an inline button which inserts the bot
+// username and query into the input field of the current chat. query may be
+// empty.
+func SwitchInlineCurrentChatButton(text, query string) *InlineKeyboardButton {
+	return &InlineKeyboardButton{Text: text, SwitchInlineQueryCurrentChat: &query}
+}
+
+// TextButton returns a reply button sending its text.
+func TextButton(text string) *KeyboardButton {
+	return &KeyboardButton{Text: text}
+}
+
+// ContactButton returns a reply button sending the user phone number.
+func ContactButton(text string) *KeyboardButton {
+	return &KeyboardButton{Text: text, RequestContact: true}
+}
+
+// LocationButton returns a reply button sending the user location.
+func LocationButton(text string) *KeyboardButton {
+	return &KeyboardButton{Text: text, RequestLocation: true}
+}
+
+// InlineKeyboard builds InlineKeyboardMarkup. The zero value is ready to use.
+//
+//	var k InlineKeyboard
+//	k.Columns(2).Add(CallbackButton("A", "a"), CallbackButton("B", "b"), CallbackButton("C", "c"))
+//	k.Row(URLButton("Docs", "https://core.telegram.org/bots"))
+//	markup, err := k.Markup() // [A B] [C] [Docs]
+type InlineKeyboard struct {
+	grid buttonGrid
+}
+
+// Columns makes Add wrap buttons into rows of n buttons. Zero n disables
+// wrapping.
+func (k *InlineKeyboard) Columns(n int) *InlineKeyboard {
+	k.grid.columns = n
+	return k
+}
+
+// Add adds buttons to the last row wrapping them according to Columns.
+func (k *InlineKeyboard) Add(buttons ...*InlineKeyboardButton) *InlineKeyboard {
+	for _, b := range buttons {
+		k.grid.add(b)
+	}
+	return k
+}
+
+// Row adds a row of buttons regardless of Columns. Following Add starts a new
+// row.
+func (k *InlineKeyboard) Row(buttons ...*InlineKeyboardButton) *InlineKeyboard {
+	row := make([]interface{}, len(buttons))
+	for i, b := range buttons {
+		row[i] = b
+	}
+	k.grid.row(row)
+	return k
+}
+
+// Markup validates buttons and returns the keyboard.
+func (k *InlineKeyboard) Markup() (*InlineKeyboardMarkup, error) {
+	err := k.grid.validate(maxInlineRowButtons, maxInlineButtons, func(v interface{}) error {
+		return validateInlineButton(v.(*InlineKeyboardButton))
+	})
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]*InlineKeyboardButton, len(k.grid.rows))
+	for i, r := range k.grid.rows {
+		rows[i] = make([]*InlineKeyboardButton, len(r))
+		for j, b := range r {
+			rows[i][j] = b.(*InlineKeyboardButton)
+		}
+	}
+	return &InlineKeyboardMarkup{InlineKeyboard: rows}, nil
+}
+
+func validateInlineButton(b *InlineKeyboardButton) error {
+	if b.Text == "" {
+		return ErrEmptyButton
+	}
+	actions := 0
+	for _, ok := range []bool{
+		b.URL != "",
+		b.CallbackData != "",
+		b.SwitchInlineQuery != nil,
+		b.SwitchInlineQueryCurrentChat != nil,
+	} {
+		if ok {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return ErrButtonAction
+	}
+	if len(b.CallbackData) > maxCallbackData {
+		return ErrCallbackDataTooLong
+	}
+	return nil
+}
+
+// ReplyKeyboard builds ReplyKeyboardMarkup. The zero value is ready to use.
+// See InlineKeyboard.
+type ReplyKeyboard struct {
+	grid buttonGrid
+
+	// Options of the built keyboard.
+	Resize    bool
+	OneTime   bool
+	Selective bool
+}
+
+// Columns makes Add wrap buttons into rows of n buttons. Zero n disables
+// wrapping.
+func (k *ReplyKeyboard) Columns(n int) *ReplyKeyboard {
+	k.grid.columns = n
+	return k
+}
+
+// Add adds buttons to the last row wrapping them according to Columns.
+func (k *ReplyKeyboard) Add(buttons ...*KeyboardButton) *ReplyKeyboard {
+	for _, b := range buttons {
+		k.grid.add(b)
+	}
+	return k
+}
+
+// Row adds a row of buttons regardless of Columns. Following Add starts a new
+// row.
+func (k *ReplyKeyboard) Row(buttons ...*KeyboardButton) *ReplyKeyboard {
+	row := make([]interface{}, len(buttons))
+	for i, b := range buttons {
+		row[i] = b
+	}
+	k.grid.row(row)
+	return k
+}
+
+// Markup validates buttons and returns the keyboard.
+func (k *ReplyKeyboard) Markup() (*ReplyKeyboardMarkup, error) {
+	err := k.grid.validate(maxReplyRowButtons, maxReplyButtons, func(v interface{}) error {
+		if v.(*KeyboardButton).Text == "" {
+			return ErrEmptyButton
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]*KeyboardButton, len(k.grid.rows))
+	for i, r := range k.grid.rows {
+		rows[i] = make([]*KeyboardButton, len(r))
+		for j, b := range r {
+			rows[i][j] = b.(*KeyboardButton)
+		}
+	}
+	return &ReplyKeyboardMarkup{
+		Keyboard:        rows,
+		ResizeKeyboard:  k.Resize,
+		OneTimeKeyboard: k.OneTime,
+		Selective:       k.Selective,
+	}, nil
+}
+
+// buttonGrid lays out buttons of any keyboard.
+type buttonGrid struct {
+	rows    [][]interface{}
+	columns int
+	closed  bool // the last row is complete
+}
+
+func (g *buttonGrid) add(b interface{}) {
+	n := len(g.rows)
+	if n == 0 || g.closed || g.columns > 0 && len(g.rows[n-1]) >= g.columns {
+		g.rows = append(g.rows, nil)
+		g.closed = false
+		n++
+	}
+	g.rows[n-1] = append(g.rows[n-1], b)
+}
+
+// row adds a complete row.
+func (g *buttonGrid) row(buttons []interface{}) {
+	if len(buttons) != 0 {
+		g.rows = append(g.rows, buttons)
+	}
+	g.closed = true
+}
+
+func (g *buttonGrid) validate(maxRow, max int, button func(interface{}) error) error {
+	total := 0
+	for i, r := range g.rows {
+		if len(r) > maxRow {
+			return &ButtonError{Row: i, Column: maxRow, Err: ErrTooManyButtons}
+		}
+		for j, b := range r {
+			if err := button(b); err != nil {
+				return &ButtonError{Row: i, Column: j, Err: err}
+			}
+		}
+		total += len(r)
+		if total > max {
+			return &ButtonError{Row: i, Column: len(r) - (total - max), Err: ErrTooManyButtons}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInlineKeyboard(t *testing.T) {
+	var k InlineKeyboard
+	k.Columns(2).
+		Add(CallbackButton("A", "a"), CallbackButton("B", "b"), CallbackButton("C", "c")).
+		Row(URLButton("Docs", "https://core.telegram.org/bots")).
+		Add(SwitchInlineButton("Share", "q"), SwitchInlineCurrentChatButton("Here", "q"))
+	m, err := k.Markup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, r := range m.InlineKeyboard {
+		var row []string
+		for _, b := range r {
+			row = append(row, b.Text)
+		}
+		got = append(got, row)
+	}
+	b, _ := json.Marshal(got)
+	if want := `[["A","B"],["C"],["Docs"],["Share","Here"]]`; string(b) != want {
+		t.Errorf("rows: want %s, got %s", want, b)
+	}
+}
+
+func TestReplyKeyboard(t *testing.T) {
+	k := ReplyKeyboard{Resize: true}
+	k.Add(TextButton("Yes"), TextButton("No")).Row(ContactButton("Phone"), LocationButton("Location"))
+	m, err := k.Markup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Keyboard) != 2 || len(m.Keyboard[0]) != 2 || !m.Keyboard[1][0].RequestContact || !m.Keyboard[1][1].RequestLocation || !m.ResizeKeyboard {
+		t.Errorf("unexpected keyboard %+v", m)
+	}
+}
+
+var InlineKeyboardErrorTests = []struct {
+	Name     string
+	Keyboard func(k *InlineKeyboard)
+	Row, Col int
+	Err      error
+}{
+	{"long data", func(k *InlineKeyboard) {
+		k.Add(CallbackButton("ok", "a"), CallbackButton("long", strings.Repeat("x", 65)))
+	}, 0, 1, ErrCallbackDataTooLong},
+	{"no text", func(k *InlineKeyboard) {
+		k.Row(CallbackButton("ok", "a")).Row(CallbackButton("", "b"))
+	}, 1, 0, ErrEmptyButton},
+	{"no action", func(k *InlineKeyboard) {
+		k.Add(&InlineKeyboardButton{Text: "a"})
+	}, 0, 0, ErrButtonAction},
+	{"two actions", func(k *InlineKeyboard) {
+		k.Add(&InlineKeyboardButton{Text: "a", URL: "http://x.com", CallbackData: "a"})
+	}, 0, 0, ErrButtonAction},
+	{"two switches", func(k *InlineKeyboard) {
+		b := SwitchInlineButton("a", "")
+		b.SwitchInlineQueryCurrentChat = b.SwitchInlineQuery
+		k.Add(b)
+	}, 0, 0, ErrButtonAction},
+	{"wide row", func(k *InlineKeyboard) {
+		for i := 0; i < 9; i++ {
+			k.Add(CallbackButton("a", "a"))
+		}
+	}, 0, 8, ErrTooManyButtons},
+	{"too many", func(k *InlineKeyboard) {
+		k.Columns(8)
+		for i := 0; i < 101; i++ {
+			k.Add(CallbackButton("a", "a"))
+		}
+	}, 12, 4, ErrTooManyButtons},
+}
+
+func TestInlineKeyboardErrors(t *testing.T) {
+	for _, tt := range InlineKeyboardErrorTests {
+		var k InlineKeyboard
+		tt.Keyboard(&k)
+		_, err := k.Markup()
+		e, ok := err.(*ButtonError)
+		if !ok {
+			t.Errorf("%s: want *ButtonError, got %v", tt.Name, err)
+			continue
+		}
+		if e.Row != tt.Row || e.Column != tt.Col || !errors.Is(err, tt.Err) {
+			t.Errorf("%s: want %v at %d:%d, got %v", tt.Name, tt.Err, tt.Row, tt.Col, err)
+		}
+	}
+}
+
+func TestSwitchInlineButtonEmptyQuery(t *testing.T) {
+	var k InlineKeyboard
+	k.Add(SwitchInlineButton("Share", ""), SwitchInlineCurrentChatButton("Here", ""))
+	m, err := k.Markup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(m.InlineKeyboard[0])
+	want := `[{"text":"Share","switch_inline_query":""},{"text":"Here","switch_inline_query_current_chat":""}]`
+	if string(b) != want {
+		t.Fatalf("buttons: want %s, got %s", want, b)
+	}
+}
@@ -6,9 +6,12 @@ import (
 )
 
 var (
-	ErrNotDeleted  = errors.New("telegram: message not deleted")
-	ErrNotEdited   = errors.New("telegram: message not edited")
-	ErrNotAnswered = errors.New("telegram: query not answered")
+	ErrNotDeleted    = errors.New("telegram: message not deleted")
+	ErrNotEdited     = errors.New("telegram: message not edited")
+	ErrNotAnswered   = errors.New("telegram: query not answered")
+	ErrWebhookNotSet = errors.New("telegram: webhook not set")
+
+	ErrCommandsNotSet = errors.New("telegram: commands not set")
 )
 
 // https://core.telegram.org/bots/api#getupdates
@@ -24,7 +27,25 @@ func (b *bot) GetUpdates(ctx context.Context, opts ...UpdatesOption) ([]*Update,
 	return v, nil
 }
 
-// SetWebhook
+// https://core.telegram.org/bots/api#setwebhook
+//
+// If w.AllowedUpdates is nil then update types of the bot are used.
+func (b *bot) SetWebhook(ctx context.Context, w *Webhook) error {
+	if w.AllowedUpdates == nil && b.updateTypes != nil {
+		c := *w
+		c.AllowedUpdates = b.updateTypes
+		w = &c
+	}
+	var ok bool
+	if err := b.do(ctx, "setWebhook", w, &ok); err != nil {
+		return err
+	}
+	if !ok {
+		return ErrWebhookNotSet
+	}
+	return nil
+}
+
 // DeleteWebhook
 // GetWebhookInfo
 // WebhookInfo
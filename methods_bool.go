@@ -27,3 +27,15 @@ func (b *bot) DeleteMessage(ctx context.Context, v *DeletedMessage) error {
 	}
 	return nil
 }
+
+// https://core.telegram.org/bots/api#setmycommands
+func (b *bot) SetMyCommands(ctx context.Context, v *MyCommands) error {
+	var ok bool
+	if err := b.do(ctx, "setMyCommands", v, &ok); err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCommandsNotSet
+	}
+	return nil
+}
@@ -7,9 +7,11 @@ header = '''package telegram
 import "context"
 '''
 
+# Method, value type and error returned when the result is false.
 methods = '''
-AnswerCallbackQuery CallbackQueryAnswer
-DeleteMessage       DeletedMessage
+AnswerCallbackQuery CallbackQueryAnswer ErrNotAnswered
+DeleteMessage       DeletedMessage      ErrNotAnswered
+SetMyCommands       MyCommands          ErrCommandsNotSet
 '''.split()
 
 method_template = '''
@@ -20,7 +22,7 @@ func (b *bot) {method}(ctx context.Context, v *{value}) error {
         return err
     }
     if !ok {
-        return ErrNotAnswered
+        return {error}
     }
     return nil
 }
@@ -35,16 +37,17 @@ def replace(template, replacements):
 
 
 def main():
-    pairs = zip(methods[::2], methods[1::2])
+    rows = zip(methods[::3], methods[1::3], methods[2::3])
 
     with open('methods_bool.go', 'w') as f:
         f.write(header)
-        for method, value in pairs:
+        for method, value, error in rows:
             api_method = method[0].lower() + method[1:]
 
             f.write(replace(method_template, {
                 '{method}': method,
                 '{value}': value,
+                '{error}': error,
                 '{api_method}': api_method,
                 '{api_method_lower}': api_method.lower(),
             }))
@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// Middleware wraps a handler to run code before and after it. Middleware of
+// handlers which are not commands receives nil *Command.
+type Middleware func(CommandFunc) CommandFunc
+
+// chain wraps fn with middleware in order of adding, so the first middleware is
+// the outermost one.
+func chain(mw []Middleware, fn CommandFunc) CommandFunc {
+	for i := len(mw) - 1; i >= 0; i-- {
+		fn = mw[i](fn)
+	}
+	return fn
+}
+
+// PanicError is an error holding a value recovered from panic.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Error implements error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprint(e.Value)
+}
+
+// Recover returns a middleware which recovers panic of a handler and returns
+// *PanicError instead.
+func Recover() Middleware {
+	return func(next CommandFunc) CommandFunc {
+		return func(c *Command, u *Update) (err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					err = &PanicError{Value: v, Stack: debug.Stack()}
+				}
+			}()
+			return next(c, u)
+		}
+	}
+}
+
+// Logger is the interface of a logger used by middleware. *log.Logger
+// satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Logging returns a middleware which logs a line for every handled command or
+// update with the sender, the chat, the result and the duration.
+func Logging(l Logger) Middleware {
+	return Timing(func(c *Command, u *Update, d time.Duration, err error) {
+		var name string
+		if c != nil {
+			name = c.Name
+		} else {
+			name = string(u.Type())
+		}
+		var userID int
+		if from := u.from(); from != nil {
+			userID = from.ID
+		}
+		var chatID int64
+		if m := u.message(); m != nil {
+			chatID = m.Chat.ID
+		}
+		result := "ok"
+		if err != nil {
+			result = "error: " + err.Error()
+		}
+		l.Printf("telegram: %s update=%d user=%d chat=%d %s (%s)", name, u.UpdateID, userID, chatID, result, d)
+	})
+}
+
+// Timing returns a middleware which calls fn with duration and result of every
+// handler call.
+func Timing(fn func(c *Command, u *Update, d time.Duration, err error)) Middleware {
+	return func(next CommandFunc) CommandFunc {
+		return func(c *Command, u *Update) error {
+			start := time.Now()
+			err := next(c, u)
+			fn(c, u, time.Since(start), err)
+			return err
+		}
+	}
+}
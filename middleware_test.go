@@ -0,0 +1,68 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func testMiddleware(name string, calls *[]string) Middleware {
+	return func(next CommandFunc) CommandFunc {
+		return func(c *Command, u *Update) error {
+			*calls = append(*calls, name)
+			return next(c, u)
+		}
+	}
+}
+
+func TestCommandsUseWrapsInOrder(t *testing.T) {
+	var calls []string
+	c := NewCommands("bot")
+	c.Use(testMiddleware("a", &calls), testMiddleware("b", &calls))
+	c.Add("/test", func(*Command, *Update) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+	c.Use(testMiddleware("c", &calls))
+
+	u := &Update{Message: &Message{
+		From:     &User{ID: 1},
+		Text:     ref("/test"),
+		Entities: []*MessageEntity{{Type: "bot_command", Offset: 0, Length: 5}},
+	}}
+	if err, ok := c.Run(u); err != nil || !ok {
+		t.Fatalf("want (nil, true), got (%v, %t)", err, ok)
+	}
+	if s := strings.Join(calls, " "); s != "a b c handler" {
+		t.Fatalf("calls: want %q, got %q", "a b c handler", s)
+	}
+}
+
+type testLogger []string
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestRouterUseRecoverAndLogging(t *testing.T) {
+	var l testLogger
+	r := NewRouter()
+	r.Use(Logging(&l), Recover())
+	r.HandleType(UpdateMessage, func(*Update) error { panic("test") })
+
+	u := &Update{UpdateID: 1, Message: &Message{From: &User{ID: 2}, Chat: Chat{ID: 3}}}
+	err, ok := r.Run(u)
+	if !ok {
+		t.Fatal("want handled update")
+	}
+	if _, isPanic := err.(*PanicError); !isPanic || err.Error() != "test" {
+		t.Fatalf("error: want *PanicError %q, got %#v", "test", err)
+	}
+	if len(l) != 1 {
+		t.Fatalf("log: want 1 line, got %d", len(l))
+	}
+	want := "telegram: message update=1 user=2 chat=3 error: test"
+	if !strings.HasPrefix(l[0], want) {
+		t.Fatalf("log: want prefix %q, got %q", want, l[0])
+	}
+}
@@ -0,0 +1,113 @@
+package telegram
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OffsetStore keeps the offset of the next update to receive. The bot saves an
+// offset after a consumer takes an update from Updates() channel, so polling
+// continues from the first unconsumed update after restart.
+type OffsetStore interface {
+	// Load returns the saved offset. It returns 0 if nothing was saved.
+	Load() (int, error)
+	Save(offset int) error
+}
+
+// NewMemoryOffsetStore returns a store which keeps an offset in memory. It is
+// used by default.
+func NewMemoryOffsetStore() OffsetStore {
+	return new(memoryOffsetStore)
+}
+
+type memoryOffsetStore struct {
+	mu     sync.Mutex
+	offset int
+}
+
+func (s *memoryOffsetStore) Load() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset, nil
+}
+
+func (s *memoryOffsetStore) Save(offset int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset = offset
+	return nil
+}
+
+// NewFileOffsetStore returns a store which keeps an offset in the file at path.
+// A missing file means no saved offset. Save syncs the file to disk, which is
+// slow, so the bot saves an offset once per received pack of updates.
+func NewFileOffsetStore(path string) OffsetStore {
+	return &fileOffsetStore{path: path}
+}
+
+type fileOffsetStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *fileOffsetStore) Load() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+func (s *fileOffsetStore) Save(offset int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeFile(s.path, []byte(strconv.Itoa(offset)))
+}
+
+// writeFile writes data to a temporary file and renames it to path. So path
+// never holds partially written data. The file and its directory are synced,
+// so the data survives a crash.
+func writeFile(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir syncs the directory at path to persist renames in it.
+func syncDir(path string) error {
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,93 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func testOffsetStore(t *testing.T, s OffsetStore) {
+	if offset, err := s.Load(); err != nil {
+		t.Fatal(err)
+	} else if offset != 0 {
+		t.Fatalf("empty store: want 0, got %d", offset)
+	}
+	for _, want := range []int{1, 100} {
+		if err := s.Save(want); err != nil {
+			t.Fatal(err)
+		}
+		if offset, err := s.Load(); err != nil {
+			t.Fatal(err)
+		} else if offset != want {
+			t.Fatalf("offset: want %d, got %d", want, offset)
+		}
+	}
+}
+
+func TestMemoryOffsetStore(t *testing.T) {
+	testOffsetStore(t, NewMemoryOffsetStore())
+}
+
+func TestFileOffsetStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "offset")
+	testOffsetStore(t, NewFileOffsetStore(path))
+	// Offset survives restart.
+	if offset, err := NewFileOffsetStore(path).Load(); err != nil {
+		t.Fatal(err)
+	} else if offset != 100 {
+		t.Fatalf("offset: want %d, got %d", 100, offset)
+	}
+}
+
+// chanOffsetStore sends saved offsets on a channel.
+type chanOffsetStore chan int
+
+func (s chanOffsetStore) Load() (int, error) { return 0, nil }
+
+func (s chanOffsetStore) Save(offset int) error {
+	s <- offset
+	return nil
+}
+
+func TestBotSavesOffsetAfterConsumer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var o struct {
+			Offset  int `json:"offset"`
+			Timeout int `json:"timeout"`
+		}
+		json.NewDecoder(r.Body).Decode(&o)
+		var result []*Update
+		switch {
+		case o.Timeout == 0:
+			// Confirmation request.
+		case o.Offset == 0:
+			result = []*Update{{UpdateID: 1}, {UpdateID: 2}}
+		default:
+			<-r.Context().Done()
+			return
+		}
+		json.NewEncoder(w).Encode(&testAPIResponse{
+			Response: apiResponse{OK: true},
+			Result:   result,
+		})
+	}))
+	defer ts.Close()
+
+	store := make(chanOffsetStore, 10)
+	b := newBot(context.Background(), "token", withURL(ts.URL+"/"), WithOffsetStore(store))
+	go b.listenToUpdates()
+	defer b.Close(context.Background())
+
+	<-b.Updates()
+	// The rest of the pack is not consumed yet.
+	if len(store) != 0 {
+		t.Fatalf("offset: want not saved, got %d", <-store)
+	}
+	<-b.Updates()
+	if offset := <-store; offset != 3 {
+		t.Fatalf("offset: want 3, got %d", offset)
+	}
+}
@@ -0,0 +1,163 @@
+package telegram
+
+import (
+	"context"
+	"strconv"
+)
+
+// Page is a page of a paginated list.
+type Page struct {
+	Text      string
+	ParseMode ParseMode
+	Entities  []*MessageEntity
+	Total     int // number of pages
+
+	// Buttons are rows added above the navigation, e.g. to select items.
+	Buttons [][]*InlineKeyboardButton
+}
+
+// PageFunc returns page n, starting from 1, of the list identified by key.
+// key is a short string chosen by the caller, e.g. a search query, and is kept
+// in callback data of the navigation buttons.
+type PageFunc func(ctx context.Context, key string, n int) (*Page, error)
+
+// Paginator sends lists split into pages with ◀ n/total ▶ navigation. The
+// message is edited in place on navigation.
+//
+//	p := NewPaginator(bot, "search", func(ctx context.Context, q string, n int) (*Page, error) { ... })
+//	p.Mount(callbacks)
+//	p.Send(ctx, chatID, query)
+type Paginator interface {
+	// Send sends the first page of the list to the chat.
+	Send(ctx context.Context, chatID int64, key string) (*Message, error)
+	// Mount adds the navigation handler to c.
+	Mount(c Callbacks)
+}
+
+// PaginatorOption is an option of NewPaginator.
+type PaginatorOption func(*paginatorOptions)
+
+type paginatorOptions struct {
+	Prev, Next string
+}
+
+// WithNavigationLabels sets labels of the buttons of the previous and the next
+// page. Defaults are "◀" and "▶".
+func WithNavigationLabels(prev, next string) PaginatorOption {
+	return func(o *paginatorOptions) {
+		o.Prev, o.Next = prev, next
+	}
+}
+
+// NewPaginator returns a paginator of pages returned by fn. prefix is the
+// callback data prefix of the navigation buttons.
+func NewPaginator(b Bot, prefix string, fn PageFunc, opts ...PaginatorOption) Paginator {
+	o := paginatorOptions{Prev: "◀", Next: "▶"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &paginator{bot: b, prefix: prefix, fn: fn, opts: o}
+}
+
+type paginator struct {
+	bot    Bot
+	prefix string
+	fn     PageFunc
+	opts   paginatorOptions
+}
+
+func (p *paginator) Send(ctx context.Context, chatID int64, key string) (*Message, error) {
+	page, markup, err := p.page(ctx, key, 1)
+	if err != nil {
+		return nil, err
+	}
+	m := &TextMessage{
+		ChatID:    chatID,
+		Text:      page.Text,
+		ParseMode: page.ParseMode,
+		Entities:  page.Entities,
+	}
+	// Avoid a typed nil interface.
+	if markup != nil {
+		m.ReplyMarkup = markup
+	}
+	return p.bot.SendMessage(ctx, m)
+}
+
+func (p *paginator) Mount(c Callbacks) {
+	c.Add(p.prefix, p.navigate)
+}
+
+// navigate edits the message of the callback query to show the requested page.
+func (p *paginator) navigate(ctx context.Context, cb *Callback) error {
+	var (
+		key string
+		n   int
+	)
+	if err := cb.Scan(&key, &n); err != nil {
+		return err
+	}
+	// The counter button has no page to navigate to.
+	m := cb.Query.Message
+	if n == 0 || m == nil {
+		return nil
+	}
+	page, markup, err := p.page(ctx, key, n)
+	if err != nil {
+		return err
+	}
+	_, err = p.bot.EditMessageText(ctx, &MessageText{
+		ChatID:      m.Chat.ID,
+		MessageID:   m.MessageID,
+		Text:        page.Text,
+		ParseMode:   page.ParseMode,
+		Entities:    page.Entities,
+		ReplyMarkup: markup,
+	})
+	return err
+}
+
+// page returns page n and its keyboard.
+func (p *paginator) page(ctx context.Context, key string, n int) (*Page, *InlineKeyboardMarkup, error) {
+	page, err := p.fn(ctx, key, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	var k InlineKeyboard
+	for _, row := range page.Buttons {
+		k.Row(row...)
+	}
+	if page.Total > 1 {
+		var nav []*InlineKeyboardButton
+		add := func(text string, to int) error {
+			data, err := PackCallbackData(p.prefix, key, to)
+			if err != nil {
+				return err
+			}
+			nav = append(nav, CallbackButton(text, data))
+			return nil
+		}
+		if n > 1 {
+			if err := add(p.opts.Prev, n-1); err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := add(strconv.Itoa(n)+"/"+strconv.Itoa(page.Total), 0); err != nil {
+			return nil, nil, err
+		}
+		if n < page.Total {
+			if err := add(p.opts.Next, n+1); err != nil {
+				return nil, nil, err
+			}
+		}
+		k.Row(nav...)
+	}
+	if len(k.grid.rows) == 0 {
+		return page, nil, nil
+	}
+	markup, err := k.Markup()
+	if err != nil {
+		return nil, nil, err
+	}
+	return page, markup, nil
+}
@@ -0,0 +1,79 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// testButtons returns texts and callback data of buttons of a sent keyboard.
+func testButtons(t *testing.T, v interface{}) [][]string {
+	var m InlineKeyboardMarkup
+	b, _ := json.Marshal(v)
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	var rows [][]string
+	for _, r := range m.InlineKeyboard {
+		var row []string
+		for _, b := range r {
+			row = append(row, b.Text+"="+b.CallbackData)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestPaginator(t *testing.T) {
+	b, reqc, closeBot := newTestBot(t, func(req *testRequest) interface{} {
+		if req.Method == "answerCallbackQuery" {
+			return true
+		}
+		return &Message{}
+	})
+	defer closeBot()
+
+	p := NewPaginator(b, "list", func(_ context.Context, key string, n int) (*Page, error) {
+		return &Page{Text: fmt.Sprintf("%s %d", key, n), Total: 3}, nil
+	})
+	c := NewCallbacks(b)
+	p.Mount(c)
+
+	if _, err := p.Send(context.Background(), 1, "q"); err != nil {
+		t.Fatal(err)
+	}
+	req := <-reqc
+	if req.Method != "sendMessage" || req.Body["text"] != "q 1" {
+		t.Fatalf("request: want first page sent, got %+v", req)
+	}
+	if got, want := fmt.Sprint(testButtons(t, req.Body["reply_markup"])), "[[1/3=list:q:0 ▶=list:q:2]]"; got != want {
+		t.Fatalf("buttons: want %s, got %s", want, got)
+	}
+
+	u := &Update{CallbackQuery: &CallbackQuery{
+		ID:      "1",
+		Data:    ref("list:q:2"),
+		Message: &Message{MessageID: 5, Chat: Chat{ID: 1}},
+	}}
+	if err, ok := c.Run(u); err != nil || !ok {
+		t.Fatalf("want (nil, true), got (%v, %t)", err, ok)
+	}
+	req = <-reqc
+	if req.Method != "editMessageText" || req.Body["text"] != "q 2" || req.Body["message_id"] != 5.0 {
+		t.Fatalf("request: want second page edited, got %+v", req)
+	}
+	if got, want := fmt.Sprint(testButtons(t, req.Body["reply_markup"])), "[[◀=list:q:1 2/3=list:q:0 ▶=list:q:3]]"; got != want {
+		t.Fatalf("buttons: want %s, got %s", want, got)
+	}
+	if req := <-reqc; req.Method != "answerCallbackQuery" {
+		t.Fatalf("request: want query answered, got %+v", req)
+	}
+
+	// The counter does nothing.
+	u.CallbackQuery.Data = ref("list:q:0")
+	c.Run(u)
+	if req := <-reqc; req.Method != "answerCallbackQuery" {
+		t.Fatalf("request: want query answered only, got %+v", req)
+	}
+}
@@ -0,0 +1,386 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FormatError is returned when a formatted text cannot be parsed.
+type FormatError struct {
+	Pos int // byte offset in the formatted text
+	Msg string
+}
+
+// Error implements error interface.
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("telegram: %s at %d", e.Msg, e.Pos)
+}
+
+// entityParser accumulates text and entities of a formatted text.
+type entityParser struct {
+	text     strings.Builder
+	offset   int // in UTF-16 codes
+	entities []*MessageEntity
+	stack    []*MessageEntity
+}
+
+func (p *entityParser) write(s string) {
+	p.text.WriteString(s)
+	p.offset += utf16Len(s)
+}
+
+// open opens an entity at the current offset.
+func (p *entityParser) open(e *MessageEntity) {
+	e.Offset = p.offset
+	p.entities = append(p.entities, e)
+	p.stack = append(p.stack, e)
+}
+
+// top returns the innermost open entity or nil.
+func (p *entityParser) top() *MessageEntity {
+	if len(p.stack) == 0 {
+		return nil
+	}
+	return p.stack[len(p.stack)-1]
+}
+
+// close closes the innermost open entity.
+func (p *entityParser) close() *MessageEntity {
+	e := p.top()
+	p.stack = p.stack[:len(p.stack)-1]
+	e.Length = p.offset - e.Offset
+	return e
+}
+
+// result returns the text and non-empty entities.
+func (p *entityParser) result() (string, []*MessageEntity) {
+	var entities []*MessageEntity
+	for _, e := range p.entities {
+		if e.Length > 0 {
+			entities = append(entities, e)
+		}
+	}
+	return p.text.String(), entities
+}
+
+// entityFromURL returns an entity for a link to url. Links to users become
+// text mentions.
+func entityFromURL(url string) *MessageEntity {
+	if s := strings.TrimPrefix(url, "tg://user?id="); s != url {
+		if id, err := strconv.Atoi(s); err == nil {
+			return &MessageEntity{Type: EntityTextMention, User: &User{ID: id}}
+		}
+	}
+	return &MessageEntity{Type: EntityTextLink, URL: &url}
+}
+
+var (
+	htmlTag  = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9-]*)((?:\s+[a-zA-Z][a-zA-Z0-9-]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'>]+))?)*)\s*(/?)>`)
+	htmlAttr = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9-]*)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+))?`)
+)
+
+// ParseHTML parses text formatted for ModeHTML into plain text and entities.
+// https://core.telegram.org/bots/api#html-style
+func ParseHTML(s string) (string, []*MessageEntity, error) {
+	var p entityParser
+	// open tags; a tag may only set a language of the parent pre
+	type tag struct {
+		name   string
+		entity bool
+	}
+	var tags []tag
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			j := strings.IndexByte(s[i:], '<')
+			if j == -1 {
+				j = len(s) - i
+			}
+			p.write(html.UnescapeString(s[i : i+j]))
+			i += j
+			continue
+		}
+
+		m := htmlTag.FindStringSubmatch(s[i:])
+		if m == nil {
+			return "", nil, &FormatError{i, "invalid tag"}
+		}
+		closing, name, selfClosing := m[1] == "/", strings.ToLower(m[2]), m[4] == "/"
+		if selfClosing {
+			return "", nil, &FormatError{i, "unsupported tag " + name}
+		}
+		if closing {
+			if len(tags) == 0 || tags[len(tags)-1].name != name {
+				return "", nil, &FormatError{i, "unexpected end tag " + name}
+			}
+			if tags[len(tags)-1].entity {
+				p.close()
+			}
+			tags = tags[:len(tags)-1]
+			i += len(m[0])
+			continue
+		}
+
+		attrs := map[string]string{}
+		for _, a := range htmlAttr.FindAllStringSubmatch(m[3], -1) {
+			v := a[2]
+			if len(v) > 1 && (v[0] == '"' || v[0] == '\'') {
+				v = v[1 : len(v)-1]
+			}
+			attrs[strings.ToLower(a[1])] = html.UnescapeString(v)
+		}
+		if pre := p.top(); name == "code" && pre != nil && pre.Type == EntityPre && pre.Offset == p.offset {
+			if lang := strings.TrimPrefix(attrs["class"], "language-"); lang != "" {
+				pre.Language = &lang
+			}
+			tags = append(tags, tag{name, false})
+			i += len(m[0])
+			continue
+		}
+		e, err := htmlEntity(name, attrs)
+		if err != nil {
+			return "", nil, &FormatError{i, err.Error()}
+		}
+		p.open(e)
+		tags = append(tags, tag{name, true})
+		i += len(m[0])
+	}
+	if len(tags) != 0 {
+		return "", nil, &FormatError{len(s), "unclosed tag " + tags[len(tags)-1].name}
+	}
+	text, entities := p.result()
+	return text, entities, nil
+}
+
+// htmlEntity returns an entity opened by the tag.
+func htmlEntity(name string, attrs map[string]string) (*MessageEntity, error) {
+	var typ string
+	switch name {
+	case "b", "strong":
+		typ = EntityBold
+	case "i", "em":
+		typ = EntityItalic
+	case "u", "ins":
+		typ = EntityUnderline
+	case "s", "strike", "del":
+		typ = EntityStrikethrough
+	case "tg-spoiler":
+		typ = EntitySpoiler
+	case "span":
+		if attrs["class"] != "tg-spoiler" {
+			return nil, fmt.Errorf("unsupported tag %s", name)
+		}
+		typ = EntitySpoiler
+	case "blockquote":
+		typ = EntityBlockquote
+		if _, ok := attrs["expandable"]; ok {
+			typ = EntityExpandableBlockquote
+		}
+	case "pre":
+		typ = EntityPre
+	case "code":
+		typ = EntityCode
+	case "a":
+		href, ok := attrs["href"]
+		if !ok {
+			return nil, fmt.Errorf("missing href of %s", name)
+		}
+		return entityFromURL(href), nil
+	case "tg-emoji":
+		id, ok := attrs["emoji-id"]
+		if !ok {
+			return nil, fmt.Errorf("missing emoji-id of %s", name)
+		}
+		return &MessageEntity{Type: EntityCustomEmoji, CustomEmojiID: &id}, nil
+	default:
+		return nil, fmt.Errorf("unsupported tag %s", name)
+	}
+	return &MessageEntity{Type: typ}, nil
+}
+
+// ParseMarkdownV2 parses text formatted for ModeMarkdownV2 into plain text and
+// entities.
+// https://core.telegram.org/bots/api#markdownv2-style
+func ParseMarkdownV2(s string) (string, []*MessageEntity, error) {
+	var p entityParser
+	var quote *MessageEntity
+	lineEnd := 0 // offset of the last line end
+	toggle := func(typ string, i int) error {
+		for _, e := range p.stack {
+			if e.Type != typ {
+				continue
+			}
+			if e != p.top() {
+				return &FormatError{i, "overlapping " + typ}
+			}
+			p.close()
+			return nil
+		}
+		p.open(&MessageEntity{Type: typ})
+		return nil
+	}
+
+	for i := 0; i < len(s); {
+		if i == 0 || s[i-1] == '\n' {
+			switch {
+			case strings.HasPrefix(s[i:], "**>") && quote == nil:
+				quote = &MessageEntity{Type: EntityExpandableBlockquote}
+				p.open(quote)
+				i += 3
+				continue
+			case s[i] == '>':
+				if quote == nil {
+					quote = &MessageEntity{Type: EntityBlockquote}
+					p.open(quote)
+				}
+				i++
+				continue
+			case quote != nil:
+				// The blockquote ends before the line end.
+				if quote != p.top() {
+					return "", nil, &FormatError{i, "unclosed entity in blockquote"}
+				}
+				p.stack = p.stack[:len(p.stack)-1]
+				quote.Length = lineEnd - quote.Offset
+				quote = nil
+			}
+		}
+
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(s) {
+				return "", nil, &FormatError{i, "trailing backslash"}
+			}
+			_, n := utf8.DecodeRuneInString(s[i+1:])
+			p.write(s[i+1 : i+1+n])
+			i += 1 + n
+		case c == '\r':
+			i++
+		case c == '\n':
+			lineEnd = p.offset
+			p.write("\n")
+			i++
+		case c == '*':
+			if err := toggle(EntityBold, i); err != nil {
+				return "", nil, err
+			}
+			i++
+		case strings.HasPrefix(s[i:], "__"):
+			if err := toggle(EntityUnderline, i); err != nil {
+				return "", nil, err
+			}
+			i += 2
+		case c == '_':
+			if err := toggle(EntityItalic, i); err != nil {
+				return "", nil, err
+			}
+			i++
+		case c == '~':
+			if err := toggle(EntityStrikethrough, i); err != nil {
+				return "", nil, err
+			}
+			i++
+		case quote != nil && quote.Type == EntityExpandableBlockquote && quote == p.top() &&
+			(s[i:] == "||" || strings.HasPrefix(s[i:], "||\n")):
+			// "||" ends an expandable blockquote at a line end.
+			p.close()
+			quote = nil
+			i += 2
+		case strings.HasPrefix(s[i:], "||"):
+			if err := toggle(EntitySpoiler, i); err != nil {
+				return "", nil, err
+			}
+			i += 2
+		case strings.HasPrefix(s[i:], "```"):
+			e := &MessageEntity{Type: EntityPre}
+			j := i + 3
+			if k := strings.IndexByte(s[j:], '\n'); k != -1 && !strings.ContainsAny(s[j:j+k], " `") {
+				if k > 0 {
+					lang := s[j : j+k]
+					e.Language = &lang
+				}
+				j += k + 1
+			}
+			code, n, ok := parseMarkdownV2Code(s[j:], "```")
+			if !ok {
+				return "", nil, &FormatError{i, "unclosed pre"}
+			}
+			p.open(e)
+			p.write(strings.TrimSuffix(code, "\n"))
+			p.close()
+			i = j + n
+		case c == '`':
+			code, n, ok := parseMarkdownV2Code(s[i+1:], "`")
+			if !ok {
+				return "", nil, &FormatError{i, "unclosed code"}
+			}
+			p.open(&MessageEntity{Type: EntityCode})
+			p.write(code)
+			p.close()
+			i += 1 + n
+		case c == '[' || strings.HasPrefix(s[i:], "!["):
+			e := &MessageEntity{Type: EntityTextLink}
+			if c == '!' {
+				e.Type = EntityCustomEmoji
+				i++
+			}
+			p.open(e)
+			i++
+		case c == ']':
+			e := p.top()
+			if e == nil || e.Type != EntityTextLink && e.Type != EntityCustomEmoji {
+				return "", nil, &FormatError{i, "unexpected ]"}
+			}
+			if !strings.HasPrefix(s[i+1:], "(") {
+				return "", nil, &FormatError{i, "missing link URL"}
+			}
+			url, n, ok := parseMarkdownV2Code(s[i+2:], ")")
+			if !ok {
+				return "", nil, &FormatError{i, "unclosed link URL"}
+			}
+			if e.Type == EntityCustomEmoji {
+				id := strings.TrimPrefix(url, "tg://emoji?id=")
+				e.CustomEmojiID = &id
+			} else {
+				link := entityFromURL(url)
+				e.Type, e.URL, e.User = link.Type, link.URL, link.User
+			}
+			p.close()
+			i += 2 + n
+		default:
+			_, n := utf8.DecodeRuneInString(s[i:])
+			p.write(s[i : i+n])
+			i += n
+		}
+	}
+	if quote != nil && quote == p.top() {
+		p.close()
+	}
+	if e := p.top(); e != nil {
+		return "", nil, &FormatError{len(s), "unclosed " + e.Type}
+	}
+	text, entities := p.result()
+	return text, entities, nil
+}
+
+// parseMarkdownV2Code returns unescaped s up to end, which is not included,
+// and the number of consumed bytes including end.
+func parseMarkdownV2Code(s, end string) (string, int, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case strings.HasPrefix(s[i:], end):
+			return b.String(), i + len(end), true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, false
+}
@@ -0,0 +1,121 @@
+package telegram
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var ParseTests = []struct {
+	Name     string
+	Format   string
+	Parse    func(string) (string, []*MessageEntity, error)
+	Text     string
+	Entities string // JSON
+}{
+	{
+		"html",
+		`<b>bold <i>it&amp;al</i></b> <a href="http://x.com/?a=1&amp;b=2">link</a> <a href="tg://user?id=7">u</a>`,
+		ParseHTML,
+		"bold it&al link u",
+		`[{"type":"bold","offset":0,"length":10},{"type":"italic","offset":5,"length":5},` +
+			`{"type":"text_link","offset":11,"length":4,"url":"http://x.com/?a=1\u0026b=2"},` +
+			`{"type":"text_mention","offset":16,"length":1,"user":{"id":7,"first_name":"","last_name":null,"username":null,"language_code":null}}]`,
+	},
+	{
+		"html pre",
+		`👍<pre><code class="language-go">x &lt; y</code></pre><span class="tg-spoiler">s</span><tg-emoji emoji-id="5">👍</tg-emoji>`,
+		ParseHTML,
+		"👍x < ys👍",
+		`[{"type":"pre","offset":2,"length":5,"language":"go"},{"type":"spoiler","offset":7,"length":1},` +
+			`{"type":"custom_emoji","offset":8,"length":2,"custom_emoji_id":"5"}]`,
+	},
+	{
+		"markdownv2",
+		`*bold _it\_al_*\. ___u_` + "\r" + `__ [link](http://x.com/(a\)) ||s||`,
+		ParseMarkdownV2,
+		"bold it_al. u link s",
+		`[{"type":"bold","offset":0,"length":10},{"type":"italic","offset":5,"length":5},` +
+			`{"type":"underline","offset":12,"length":1},{"type":"italic","offset":12,"length":1},` +
+			`{"type":"text_link","offset":14,"length":4,"url":"http://x.com/(a)"},{"type":"spoiler","offset":19,"length":1}]`,
+	},
+	{
+		"markdownv2 code",
+		"`a\\`b` ```go\nx := `1`\n``` ![👍](tg://emoji?id=5)\n>quote\n>more\nend",
+		ParseMarkdownV2,
+		"a`b x := `1` 👍\nquote\nmore\nend",
+		`[{"type":"code","offset":0,"length":3},{"type":"pre","offset":4,"length":8,"language":"go"},` +
+			`{"type":"custom_emoji","offset":13,"length":2,"custom_emoji_id":"5"},{"type":"blockquote","offset":16,"length":10}]`,
+	},
+	{
+		"markdownv2 expandable blockquote",
+		"**>a ||s||\n>b||\nc",
+		ParseMarkdownV2,
+		"a s\nb\nc",
+		`[{"type":"expandable_blockquote","offset":0,"length":5},{"type":"spoiler","offset":2,"length":1}]`,
+	},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range ParseTests {
+		text, entities, err := tt.Parse(tt.Format)
+		if err != nil {
+			t.Errorf("%s: %v", tt.Name, err)
+			continue
+		}
+		if text != tt.Text {
+			t.Errorf("%s: got text %q, want %q", tt.Name, text, tt.Text)
+		}
+		if b, _ := json.Marshal(entities); string(b) != tt.Entities {
+			t.Errorf("%s: got entities\n%s\nwant\n%s", tt.Name, b, tt.Entities)
+		}
+	}
+}
+
+var ParseErrorTests = []struct {
+	Name   string
+	Format string
+	Parse  func(string) (string, []*MessageEntity, error)
+}{
+	{"html unclosed", "<b>a", ParseHTML},
+	{"html mismatched", "<b><i>a</b></i>", ParseHTML},
+	{"html unsupported", "<div>a</div>", ParseHTML},
+	{"html invalid", "a < b", ParseHTML},
+	{"markdownv2 unclosed", "*a", ParseMarkdownV2},
+	{"markdownv2 overlapping", "*a _b* c_", ParseMarkdownV2},
+	{"markdownv2 code", "`a", ParseMarkdownV2},
+	{"markdownv2 link", "[a]", ParseMarkdownV2},
+}
+
+func TestParseError(t *testing.T) {
+	for _, tt := range ParseErrorTests {
+		if _, _, err := tt.Parse(tt.Format); err == nil {
+			t.Errorf("%s: want error", tt.Name)
+		} else if _, ok := err.(*FormatError); !ok {
+			t.Errorf("%s: got %T, want *FormatError", tt.Name, err)
+		}
+	}
+}
+
+// Rendered entities must be parsed back.
+func TestParseRender(t *testing.T) {
+	for _, tt := range RenderTests {
+		if tt.Name == "out of range" {
+			continue
+		}
+		for _, f := range []struct {
+			Name   string
+			Format string
+			Parse  func(string) (string, []*MessageEntity, error)
+		}{
+			{"html", tt.HTML, ParseHTML},
+			{"markdownv2", tt.MarkdownV2, ParseMarkdownV2},
+		} {
+			text, _, err := f.Parse(f.Format)
+			if err != nil {
+				t.Errorf("%s: %s: %v", tt.Name, f.Name, err)
+			} else if text != tt.Text {
+				t.Errorf("%s: %s: got %q, want %q", tt.Name, f.Name, text, tt.Text)
+			}
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package telegram
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// RenderHTML returns text with entities formatted for ModeHTML, e.g. to resend
+// a received message keeping its formatting. Entities may be nested or
+// overlap. Entities Telegram detects itself, like mentions and URLs, are
+// rendered as plain text.
+// https://core.telegram.org/bots/api#html-style
+func RenderHTML(text string, entities []*MessageEntity) string {
+	return render(text, entities, htmlFormat)
+}
+
+// RenderMarkdownV2 returns text with entities formatted for ModeMarkdownV2.
+// See RenderHTML.
+// https://core.telegram.org/bots/api#markdownv2-style
+func RenderMarkdownV2(text string, entities []*MessageEntity) string {
+	return render(text, entities, markdownV2Format)
+}
+
+// entityFormat defines how entities are rendered in a parse mode. open and
+// close return an empty string for unsupported entities.
+type entityFormat struct {
+	open   func(e *MessageEntity) string
+	close  func(e *MessageEntity) string
+	escape func(s string, code bool) string
+	// line returns a prefix of every line of e after the first one, e.g. ">"
+	// for blockquotes in MarkdownV2.
+	line func(e *MessageEntity) string
+	// separate returns a separator between adjacent tags s and t. It resolves
+	// ambiguities like "___" in MarkdownV2.
+	separate func(s, t string) string
+}
+
+// render renders text with entities in format f.
+//
+// Entities are opened in order of offsets, outer ones first. An entity is
+// closed at its end; entities opened after it are closed first and reopened
+// right after, so overlapping entities become correctly nested tags.
+func render(text string, entities []*MessageEntity, f entityFormat) string {
+	units := utf16.Encode([]rune(text))
+	n := len(units)
+
+	var sorted []*MessageEntity
+	for _, e := range entities {
+		if e.Length > 0 && e.Offset >= 0 && e.Offset < n && f.open(e) != "" {
+			sorted = append(sorted, e)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Offset != sorted[j].Offset {
+			return sorted[i].Offset < sorted[j].Offset
+		}
+		return sorted[i].Length > sorted[j].Length
+	})
+	end := func(e *MessageEntity) int {
+		if e.Offset+e.Length > n {
+			return n
+		}
+		return e.Offset + e.Length
+	}
+
+	var (
+		b     strings.Builder
+		last  string // last written tag
+		stack []*MessageEntity
+	)
+	write := func(tag string) {
+		if last != "" {
+			b.WriteString(f.separate(last, tag))
+		}
+		b.WriteString(tag)
+		last = tag
+	}
+	code := func() bool {
+		for _, e := range stack {
+			if e.Type == EntityCode || e.Type == EntityPre {
+				return true
+			}
+		}
+		return false
+	}
+
+	pos, next := 0, 0
+	for pos < n {
+		// Close entities ending at pos.
+		i := len(stack)
+		for j, e := range stack {
+			if end(e) == pos {
+				i = j
+				break
+			}
+		}
+		reopen := append([]*MessageEntity(nil), stack[i:]...)
+		for j := len(stack) - 1; j >= i; j-- {
+			write(f.close(stack[j]))
+		}
+		stack = stack[:i]
+		for _, e := range reopen {
+			if end(e) > pos {
+				write(f.open(e))
+				stack = append(stack, e)
+			}
+		}
+
+		// Open entities starting at pos.
+		for ; next < len(sorted) && sorted[next].Offset == pos; next++ {
+			write(f.open(sorted[next]))
+			stack = append(stack, sorted[next])
+		}
+
+		// Write text up to the next boundary.
+		to := n
+		if next < len(sorted) {
+			to = sorted[next].Offset
+		}
+		for _, e := range stack {
+			if end(e) < to {
+				to = end(e)
+			}
+		}
+		s := f.escape(string(utf16.Decode(units[pos:to])), code())
+		for _, e := range stack {
+			if prefix := f.line(e); prefix != "" {
+				// A line break ending e starts a line outside of it.
+				tail := end(e) == to && strings.HasSuffix(s, "\n")
+				if tail {
+					s = s[:len(s)-1]
+				}
+				s = strings.ReplaceAll(s, "\n", "\n"+prefix)
+				if tail {
+					s += "\n"
+				}
+				break
+			}
+		}
+		b.WriteString(s)
+		last = ""
+		pos = to
+	}
+	for j := len(stack) - 1; j >= 0; j-- {
+		write(f.close(stack[j]))
+	}
+	return b.String()
+}
+
+var htmlFormat = entityFormat{
+	open: func(e *MessageEntity) string {
+		switch e.Type {
+		case EntityBold:
+			return "<b>"
+		case EntityItalic:
+			return "<i>"
+		case EntityUnderline:
+			return "<u>"
+		case EntityStrikethrough:
+			return "<s>"
+		case EntitySpoiler:
+			return "<tg-spoiler>"
+		case EntityBlockquote:
+			return "<blockquote>"
+		case EntityExpandableBlockquote:
+			return "<blockquote expandable>"
+		case EntityCode:
+			return "<code>"
+		case EntityPre:
+			if e.Language != nil && *e.Language != "" {
+				return `<pre><code class="language-` + EscapeHTML(*e.Language) + `">`
+			}
+			return "<pre>"
+		case EntityTextLink:
+			if e.URL != nil {
+				return `<a href="` + EscapeHTML(*e.URL) + `">`
+			}
+		case EntityTextMention:
+			if e.User != nil {
+				return `<a href="` + userURL(e.User) + `">`
+			}
+		case EntityCustomEmoji:
+			if e.CustomEmojiID != nil {
+				return `<tg-emoji emoji-id="` + EscapeHTML(*e.CustomEmojiID) + `">`
+			}
+		}
+		return ""
+	},
+	close: func(e *MessageEntity) string {
+		switch e.Type {
+		case EntityBold:
+			return "</b>"
+		case EntityItalic:
+			return "</i>"
+		case EntityUnderline:
+			return "</u>"
+		case EntityStrikethrough:
+			return "</s>"
+		case EntitySpoiler:
+			return "</tg-spoiler>"
+		case EntityBlockquote, EntityExpandableBlockquote:
+			return "</blockquote>"
+		case EntityCode:
+			return "</code>"
+		case EntityPre:
+			if e.Language != nil && *e.Language != "" {
+				return "</code></pre>"
+			}
+			return "</pre>"
+		case EntityTextLink, EntityTextMention:
+			return "</a>"
+		case EntityCustomEmoji:
+			return "</tg-emoji>"
+		}
+		return ""
+	},
+	escape: func(s string, _ bool) string {
+		return EscapeHTML(s)
+	},
+	separate: func(string, string) string {
+		return ""
+	},
+	line: func(*MessageEntity) string {
+		return ""
+	},
+}
+
+var markdownV2Format = entityFormat{
+	open: markdownV2Open,
+	close: func(e *MessageEntity) string {
+		switch e.Type {
+		case EntityPre:
+			return "\n```"
+		case EntityTextLink:
+			return "](" + EscapeMarkdownV2URL(*e.URL) + ")"
+		case EntityTextMention:
+			return "](" + userURL(e.User) + ")"
+		case EntityCustomEmoji:
+			return "](tg://emoji?id=" + EscapeMarkdownV2URL(*e.CustomEmojiID) + ")"
+		case EntityBlockquote:
+			return ""
+		case EntityExpandableBlockquote:
+			return "||"
+		}
+		return markdownV2Open(e)
+	},
+	escape: func(s string, code bool) string {
+		if code {
+			return EscapeMarkdownV2Code(s)
+		}
+		return EscapeMarkdownV2(s)
+	},
+	separate: func(s, t string) string {
+		// "__" is greedily parsed as underline, so italic "_" followed by
+		// another "_" must be separated.
+		if s == "_" && strings.HasPrefix(t, "_") {
+			return "\r"
+		}
+		return ""
+	},
+	line: func(e *MessageEntity) string {
+		if e.Type == EntityBlockquote || e.Type == EntityExpandableBlockquote {
+			return ">"
+		}
+		return ""
+	},
+}
+
+// markdownV2Open returns an opening tag of e in MarkdownV2. Closing tags of
+// most entities are the same.
+func markdownV2Open(e *MessageEntity) string {
+	switch e.Type {
+	case EntityBold:
+		return "*"
+	case EntityItalic:
+		return "_"
+	case EntityUnderline:
+		return "__"
+	case EntityStrikethrough:
+		return "~"
+	case EntitySpoiler:
+		return "||"
+	case EntityBlockquote:
+		return ">"
+	case EntityExpandableBlockquote:
+		return "**>"
+	case EntityCode:
+		return "`"
+	case EntityPre:
+		if e.Language != nil {
+			return "```" + EscapeMarkdownV2Code(*e.Language) + "\n"
+		}
+		return "```\n"
+	case EntityTextLink:
+		if e.URL != nil {
+			return "["
+		}
+	case EntityTextMention:
+		if e.User != nil {
+			return "["
+		}
+	case EntityCustomEmoji:
+		if e.CustomEmojiID != nil {
+			return "!["
+		}
+	}
+	return ""
+}
+
+// userURL returns a URL mentioning u.
+func userURL(u *User) string {
+	return "tg://user?id=" + strconv.Itoa(u.ID)
+}
@@ -0,0 +1,116 @@
+package telegram
+
+import "testing"
+
+func entity(typ string, offset, length int) *MessageEntity {
+	return &MessageEntity{Type: typ, Offset: offset, Length: length}
+}
+
+var RenderTests = []struct {
+	Name       string
+	Text       string
+	Entities   []*MessageEntity
+	HTML       string
+	MarkdownV2 string
+}{
+	{
+		"plain",
+		"a < b & c. d_e",
+		nil,
+		"a &lt; b &amp; c. d_e",
+		`a < b & c\. d\_e`,
+	},
+	{
+		"nested",
+		"bold italic",
+		[]*MessageEntity{entity("italic", 5, 6), entity("bold", 0, 11)},
+		"<b>bold <i>italic</i></b>",
+		"*bold _italic_*",
+	},
+	{
+		"overlapping",
+		"abcdef",
+		[]*MessageEntity{entity("bold", 0, 4), entity("italic", 2, 4)},
+		"<b>ab<i>cd</i></b><i>ef</i>",
+		"*ab_cd_*_ef_",
+	},
+	{
+		"utf16",
+		"👍 ok! 👍",
+		[]*MessageEntity{entity("bold", 3, 3), entity("mention", 0, 2)},
+		"👍 <b>ok!</b> 👍",
+		`👍 *ok\!* 👍`,
+	},
+	{
+		"underline italic",
+		"ab",
+		[]*MessageEntity{entity("underline", 0, 2), entity("italic", 0, 2)},
+		"<u><i>ab</i></u>",
+		"___ab_\r__",
+	},
+	{
+		"code",
+		"run `a\\b` (now)",
+		[]*MessageEntity{entity("code", 4, 5)},
+		"run <code>`a\\b`</code> (now)",
+		"run `\\`a\\\\b\\`` \\(now\\)",
+	},
+	{
+		"pre",
+		"x := <y>",
+		[]*MessageEntity{{Type: "pre", Offset: 0, Length: 8, Language: ref("go")}},
+		`<pre><code class="language-go">x := &lt;y&gt;</code></pre>`,
+		"```go\nx := <y>\n```",
+	},
+	{
+		"links",
+		"site user 👍",
+		[]*MessageEntity{
+			{Type: "text_link", Offset: 0, Length: 4, URL: ref("http://x.com/(a)?b=\"c\"")},
+			{Type: "text_mention", Offset: 5, Length: 4, User: &User{ID: 42}},
+			{Type: "custom_emoji", Offset: 10, Length: 2, CustomEmojiID: ref("123")},
+		},
+		`<a href="http://x.com/(a)?b=&quot;c&quot;">site</a> <a href="tg://user?id=42">user</a> <tg-emoji emoji-id="123">👍</tg-emoji>`,
+		`[site](http://x.com/(a\)?b="c") [user](tg://user?id=42) ![👍](tg://emoji?id=123)`,
+	},
+	{
+		"out of range",
+		"ab",
+		[]*MessageEntity{entity("bold", 1, 10), entity("italic", 5, 1), entity("bold", 0, 0)},
+		"a<b>b</b>",
+		"a*b*",
+	},
+	{
+		"blockquote",
+		"quote\nlines\nafter",
+		[]*MessageEntity{entity("blockquote", 0, 12), entity("bold", 6, 5)},
+		"<blockquote>quote\n<b>lines</b>\n</blockquote>after",
+		">quote\n>*lines*\nafter",
+	},
+	{
+		"expandable blockquote",
+		"a\nb\nc",
+		[]*MessageEntity{entity("expandable_blockquote", 2, 3)},
+		"a\n<blockquote expandable>b\nc</blockquote>",
+		"a\n**>b\n>c||",
+	},
+}
+
+func TestRender(t *testing.T) {
+	for _, tt := range RenderTests {
+		if got := RenderHTML(tt.Text, tt.Entities); got != tt.HTML {
+			t.Errorf("%s: html: want %q, got %q", tt.Name, tt.HTML, got)
+		}
+		if got := RenderMarkdownV2(tt.Text, tt.Entities); got != tt.MarkdownV2 {
+			t.Errorf("%s: markdown v2: want %q, got %q", tt.Name, tt.MarkdownV2, got)
+		}
+	}
+}
+
+func TestRenderMarkdownV2PreLanguage(t *testing.T) {
+	entities := []*MessageEntity{{Type: "pre", Offset: 0, Length: 1, Language: ref("a`\\b")}}
+	want := "```a\\`\\\\b\nx\n```"
+	if got := RenderMarkdownV2("x", entities); got != want {
+		t.Fatalf("markdown v2: want %q, got %q", want, got)
+	}
+}
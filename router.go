@@ -0,0 +1,193 @@
+package telegram
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var ErrUnhandled = errors.New("telegram: update not handled")
+
+// Runner is the interface of an update runner. Commands and Router implement
+// it. ok is false when u is not handled.
+type Runner interface {
+	Run(*Update) (err error, ok bool)
+}
+
+var _ Runner = (Commands)(nil)
+var _ Runner = (Router)(nil)
+
+// RunFunc returns a function running r. The function returns ErrUnhandled when
+// r does not handle an update. It may be used with Dispatcher.
+func RunFunc(r Runner) UpdateFunc {
+	return func(u *Update) error {
+		err, ok := r.Run(u)
+		if !ok {
+			return ErrUnhandled
+		}
+		return err
+	}
+}
+
+// Predicate reports whether an update matches a route.
+type Predicate func(*Update) bool
+
+// OfType matches updates of any of the types.
+func OfType(types ...UpdateType) Predicate {
+	return func(u *Update) bool {
+		t := u.Type()
+		for i := range types {
+			if types[i] == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// TextMatches matches messages of any kind with text matching re.
+func TextMatches(re *regexp.Regexp) Predicate {
+	return func(u *Update) bool {
+		m := u.message()
+		return m != nil && m.Text != nil && re.MatchString(*m.Text)
+	}
+}
+
+// HasContent matches messages of any kind with content of any of the types.
+func HasContent(types ...ContentType) Predicate {
+	return func(u *Update) bool {
+		m := u.message()
+		if m == nil {
+			return false
+		}
+		t := m.ContentType()
+		for i := range types {
+			if types[i] == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// InChat matches updates from chats of any of the types (e.g. "private",
+// "group", "supergroup", "channel").
+func InChat(types ...string) Predicate {
+	return func(u *Update) bool {
+		m := u.message()
+		if m == nil {
+			return false
+		}
+		for i := range types {
+			if types[i] == m.Chat.Type {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// CallbackPrefix matches callback queries with data starting with prefix.
+func CallbackPrefix(prefix string) Predicate {
+	return func(u *Update) bool {
+		q := u.CallbackQuery
+		return q != nil && q.Data != nil && strings.HasPrefix(*q.Data, prefix)
+	}
+}
+
+// And matches updates matching all the predicates.
+func And(ps ...Predicate) Predicate {
+	return func(u *Update) bool {
+		for _, p := range ps {
+			if !p(u) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Router is the interface of a generic updates register/runner. Routes are
+// checked in order of adding and the first matching route handles an update.
+type Router interface {
+	Handle(p Predicate, fn UpdateFunc)
+	HandleType(t UpdateType, fn UpdateFunc)
+	Mount(r Runner)
+	Fallback(fn UpdateFunc)
+	Use(mw ...Middleware)
+	Run(*Update) (error, bool)
+}
+
+func NewRouter() Router {
+	return new(router)
+}
+
+type router struct {
+	routes     []*route
+	fallback   UpdateFunc
+	middleware []Middleware
+}
+
+// Handle adds the handler for updates matching p.
+func (r *router) Handle(p Predicate, fn UpdateFunc) {
+	r.routes = append(r.routes, &route{Predicate: p, Func: fn})
+}
+
+// HandleType adds the handler for updates of type t.
+func (r *router) HandleType(t UpdateType, fn UpdateFunc) {
+	r.Handle(OfType(t), fn)
+}
+
+// Mount adds the runner as a route (e.g. Commands or another Router). The route
+// matches when the runner handles an update. Middleware of the router is not
+// applied to the runner.
+func (r *router) Mount(runner Runner) {
+	r.routes = append(r.routes, &route{Runner: runner})
+}
+
+// Fallback sets the handler for updates not matching any route.
+func (r *router) Fallback(fn UpdateFunc) {
+	r.fallback = fn
+}
+
+// Use adds middleware wrapping handlers and the fallback. Middleware receives
+// nil *Command.
+func (r *router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Run executes the handler of the first route matching u. ok is false when no
+// route matches u and there is no fallback.
+func (r *router) Run(u *Update) (err error, ok bool) {
+	if u == nil {
+		return nil, false
+	}
+	for _, rt := range r.routes {
+		if rt.Runner != nil {
+			if err, ok = rt.Runner.Run(u); ok {
+				return
+			}
+			continue
+		}
+		if rt.Predicate(u) {
+			return r.call(rt.Func, u), true
+		}
+	}
+	if r.fallback != nil {
+		return r.call(r.fallback, u), true
+	}
+	return nil, false
+}
+
+// call executes fn wrapped by middleware.
+func (r *router) call(fn UpdateFunc, u *Update) error {
+	h := func(_ *Command, u *Update) error { return fn(u) }
+	return chain(r.middleware, h)(nil, u)
+}
+
+// route is either a handler with predicate or a mounted runner.
+type route struct {
+	Predicate Predicate
+	Func      UpdateFunc
+	Runner    Runner
+}
@@ -0,0 +1,73 @@
+package telegram
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestRouterRunsFirstMatchingRoute(t *testing.T) {
+	var got string
+	handler := func(name string) UpdateFunc {
+		return func(*Update) error {
+			got = name
+			return nil
+		}
+	}
+	r := NewRouter()
+	r.Handle(TextMatches(regexp.MustCompile(`^hi`)), handler("hi"))
+	r.Handle(And(InChat("private"), HasContent(ContentPhoto)), handler("photo"))
+	r.HandleType(UpdateEditedMessage, handler("edited"))
+	r.Handle(CallbackPrefix("page:"), handler("page"))
+	r.HandleType(UpdateMessage, handler("message"))
+
+	var tests = []struct {
+		Update *Update
+		Route  string
+	}{
+		{&Update{Message: &Message{Text: ref("hi there")}}, "hi"},
+		{&Update{EditedMessage: &Message{Text: ref("hi again")}}, "hi"},
+		{&Update{Message: &Message{Chat: Chat{Type: "private"}, Photo: []*PhotoSize{{}}}}, "photo"},
+		{&Update{Message: &Message{Chat: Chat{Type: "group"}, Photo: []*PhotoSize{{}}}}, "message"},
+		{&Update{EditedMessage: &Message{Text: ref("bye")}}, "edited"},
+		{&Update{CallbackQuery: &CallbackQuery{Data: ref("page:2")}}, "page"},
+	}
+	for _, tt := range tests {
+		got = ""
+		if err, ok := r.Run(tt.Update); err != nil || !ok {
+			t.Fatalf("%s: want (nil, true), got (%v, %t)", tt.Route, err, ok)
+		}
+		if got != tt.Route {
+			t.Fatalf("route: want %q, got %q", tt.Route, got)
+		}
+	}
+
+	// Unhandled update.
+	u := &Update{CallbackQuery: &CallbackQuery{Data: ref("other")}}
+	if _, ok := r.Run(u); ok {
+		t.Fatal("want unhandled update")
+	}
+	if err := RunFunc(r)(u); err != ErrUnhandled {
+		t.Fatalf("error: want %v, got %v", ErrUnhandled, err)
+	}
+
+	// Fallback.
+	r.Fallback(handler("fallback"))
+	if _, ok := r.Run(u); !ok || got != "fallback" {
+		t.Fatalf("fallback: want handled, got %q", got)
+	}
+}
+
+func TestRouterMount(t *testing.T) {
+	errTest := errors.New("test")
+	sub := NewRouter()
+	sub.HandleType(UpdateMessage, func(*Update) error { return errTest })
+	r := NewRouter()
+	r.Mount(sub)
+	if err, ok := r.Run(&Update{Message: &Message{}}); !ok || err != errTest {
+		t.Fatalf("want (%v, true), got (%v, %t)", errTest, err, ok)
+	}
+	if _, ok := r.Run(&Update{EditedMessage: &Message{}}); ok {
+		t.Fatal("want unhandled update")
+	}
+}
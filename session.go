@@ -0,0 +1,236 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// SessionStore is the interface of a sessions storage. A session is a set of
+// named JSON-encoded values.
+type SessionStore interface {
+	// Load returns values of the session. It returns an empty map if there is
+	// no session with the key.
+	Load(key string) (map[string]json.RawMessage, error)
+	// Save merges changes into the session. Nil values are deleted.
+	Save(key string, changes map[string]json.RawMessage) error
+}
+
+// SessionKeyFunc returns a session key for the update. ok is false if the update
+// has no session.
+type SessionKeyFunc func(*Update) (key string, ok bool)
+
+// UserSession is a SessionKeyFunc for sessions of users.
+func UserSession(u *Update) (string, bool) {
+	if from := u.from(); from != nil {
+		return "user:" + strconv.Itoa(from.ID), true
+	}
+	return "", false
+}
+
+// ChatSession is a SessionKeyFunc for sessions of chats.
+func ChatSession(u *Update) (string, bool) {
+	if m := u.message(); m != nil {
+		return "chat:" + strconv.FormatInt(m.Chat.ID, 10), true
+	}
+	return "", false
+}
+
+// Session holds values kept between updates, e.g. user preferences or drafts.
+// Changes are saved with Save. A session must not be used concurrently.
+type Session struct {
+	Key string
+
+	store   SessionStore
+	values  map[string]json.RawMessage
+	changes map[string]json.RawMessage
+}
+
+// LoadSession loads the session with the key from store.
+func LoadSession(store SessionStore, key string) (*Session, error) {
+	values, err := store.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Key: key, store: store, values: values, changes: map[string]json.RawMessage{}}, nil
+}
+
+// Get decodes the value with the name into v. ok is false if there is no such
+// value.
+func (s *Session) Get(name string, v interface{}) (ok bool, err error) {
+	b, ok := s.values[name]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(b, v)
+}
+
+// Set sets the value with the name to JSON-encoded v.
+func (s *Session) Set(name string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.values[name] = b
+	s.changes[name] = b
+	return nil
+}
+
+// Delete deletes the value with the name.
+func (s *Session) Delete(name string) {
+	delete(s.values, name)
+	s.changes[name] = nil
+}
+
+// Save saves changed values. Values changed concurrently in other sessions with
+// the same key are kept.
+func (s *Session) Save() error {
+	if len(s.changes) == 0 {
+		return nil
+	}
+	if err := s.store.Save(s.Key, s.changes); err != nil {
+		return err
+	}
+	s.changes = map[string]json.RawMessage{}
+	return nil
+}
+
+// SessionFromContext returns the session stored in ctx or nil. Handlers wrapped
+// by Sessions middleware receive a context with a session, e.g. u.Context().
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey).(*Session)
+	return s
+}
+
+// Sessions returns a middleware which loads the session of an update from
+// store before a handler and saves it after the handler returns. The session
+// is available with SessionFromContext(u.Context()) and with the context of
+// context-aware handlers. Updates without a session key are handled without a
+// session.
+//
+// Changes are discarded if the handler returns an error or panics, so a failed
+// update does not leave a half-updated session.
+func Sessions(store SessionStore, key SessionKeyFunc) Middleware {
+	return func(next CommandFunc) CommandFunc {
+		return func(c *Command, u *Update) error {
+			k, ok := key(u)
+			if !ok {
+				return next(c, u)
+			}
+			s, err := LoadSession(store, k)
+			if err != nil {
+				return err
+			}
+			ctx := context.WithValue(u.Context(), sessionKey, s)
+			if err := next(c, u.WithContext(ctx)); err != nil {
+				return err
+			}
+			return s.Save()
+		}
+	}
+}
+
+// NewMemorySessionStore returns a store which keeps sessions in memory.
+func NewMemorySessionStore() SessionStore {
+	return &memorySessionStore{m: map[string]map[string]json.RawMessage{}}
+}
+
+type memorySessionStore struct {
+	mu sync.Mutex
+	m  map[string]map[string]json.RawMessage
+}
+
+func (s *memorySessionStore) Load(key string) (map[string]json.RawMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := map[string]json.RawMessage{}
+	for k, v := range s.m[key] {
+		values[k] = v
+	}
+	return values, nil
+}
+
+func (s *memorySessionStore) Save(key string, changes map[string]json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values, ok := s.m[key]
+	if !ok {
+		values = map[string]json.RawMessage{}
+		s.m[key] = values
+	}
+	merge(values, changes)
+	return nil
+}
+
+// merge applies changes to values. Nil changes delete values.
+func merge(values, changes map[string]json.RawMessage) {
+	for k, v := range changes {
+		if v == nil {
+			delete(values, k)
+		} else {
+			values[k] = v
+		}
+	}
+}
+
+// NewFileSessionStore returns a store which keeps a session per JSON file in
+// dir. The directory must exist.
+func NewFileSessionStore(dir string) SessionStore {
+	return &fileSessionStore{dir: dir}
+}
+
+type fileSessionStore struct {
+	dir   string
+	locks sync.Map // key -> *sync.Mutex
+}
+
+// lock locks the session with the key and returns the unlock function.
+func (s *fileSessionStore) lock(key string) func() {
+	v, _ := s.locks.LoadOrStore(key, new(sync.Mutex))
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+func (s *fileSessionStore) path(key string) string {
+	return filepath.Join(s.dir, url.QueryEscape(key)+".json")
+}
+
+func (s *fileSessionStore) Load(key string) (map[string]json.RawMessage, error) {
+	defer s.lock(key)()
+	return s.load(key)
+}
+
+func (s *fileSessionStore) load(key string) (map[string]json.RawMessage, error) {
+	values := map[string]json.RawMessage{}
+	b, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (s *fileSessionStore) Save(key string, changes map[string]json.RawMessage) error {
+	defer s.lock(key)()
+	values, err := s.load(key)
+	if err != nil {
+		return err
+	}
+	merge(values, changes)
+	b, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return writeFile(s.path(key), b)
+}
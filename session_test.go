@@ -0,0 +1,139 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync/atomic"
+	"testing"
+)
+
+func testSessionStore(t *testing.T, store SessionStore) {
+	s, err := LoadSession(store, "user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.Get("lang", new(string)); ok || err != nil {
+		t.Fatalf("get: want (false, nil), got (%t, %v)", ok, err)
+	}
+	s.Set("lang", "en")
+	s.Set("count", 1)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A concurrent session changes another value.
+	other, _ := LoadSession(store, "user:1")
+	other.Set("count", 2)
+	s.Delete("lang")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = LoadSession(store, "user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lang string
+	if ok, _ := s.Get("lang", &lang); ok {
+		t.Fatalf("lang: want deleted, got %q", lang)
+	}
+	var count int
+	if ok, err := s.Get("count", &count); !ok || err != nil {
+		t.Fatalf("count: want (true, nil), got (%t, %v)", ok, err)
+	}
+	if count != 2 {
+		t.Fatalf("count: want 2, got %d", count)
+	}
+
+	s, _ = LoadSession(store, "user:2")
+	if ok, _ := s.Get("count", &count); ok {
+		t.Fatal("sessions must not be shared between keys")
+	}
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	testSessionStore(t, NewMemorySessionStore())
+}
+
+func TestFileSessionStore(t *testing.T) {
+	testSessionStore(t, NewFileSessionStore(t.TempDir()))
+}
+
+// sessionCount returns n of the session with the key.
+func sessionCount(t *testing.T, store SessionStore, key string) int {
+	s, err := LoadSession(store, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if _, err := s.Get("n", &n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSessions(t *testing.T) {
+	store := NewMemorySessionStore()
+	failed := errors.New("failed")
+	// count increments n in the session of ctx and returns fail.
+	count := func(ctx context.Context, fail error) error {
+		s := SessionFromContext(ctx)
+		if s == nil {
+			t.Error("no session in context")
+			return ErrUnhandled
+		}
+		var n int
+		if _, err := s.Get("n", &n); err != nil {
+			return err
+		}
+		if err := s.Set("n", n+1); err != nil {
+			return err
+		}
+		return fail
+	}
+
+	c := NewCommands("bot")
+	c.Use(Sessions(store, UserSession))
+	c.AddContext("/count", func(ctx context.Context, _ *Command, _ *Update) error {
+		return count(ctx, nil)
+	})
+	c.Add("/fail", func(_ *Command, u *Update) error {
+		return count(u.Context(), failed)
+	})
+
+	cb := NewCallbacks(nil)
+	cb.Use(Sessions(store, UserSession))
+	cb.Add("count", func(ctx context.Context, cb *Callback) error {
+		// Answered to skip the request of the nil bot.
+		atomic.StoreInt32(&cb.answered, 1)
+		return count(ctx, nil)
+	})
+
+	r := NewRouter()
+	r.Use(Sessions(store, UserSession))
+	r.Handle(TextMatches(regexp.MustCompile("^count$")), func(u *Update) error {
+		return count(u.Context(), nil)
+	})
+
+	if err, _ := c.Run(testMessageUpdate("/count")); err != nil {
+		t.Fatal(err)
+	}
+	// Changes of a failed handler are discarded.
+	if err, _ := c.Run(testMessageUpdate("/fail")); err != failed {
+		t.Fatalf("error: want %v, got %v", failed, err)
+	}
+	u := &Update{CallbackQuery: &CallbackQuery{ID: "1", From: User{ID: 1}, Data: ref("count")}}
+	if err, _ := cb.Run(u); err != nil {
+		t.Fatal(err)
+	}
+	if err, _ := r.Run(testMessageUpdate("count")); err != nil {
+		t.Fatal(err)
+	}
+	if n := sessionCount(t, store, "user:1"); n != 3 {
+		t.Fatalf("count: want 3, got %d", n)
+	}
+}
@@ -0,0 +1,231 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"unicode/utf16"
+)
+
+// Length limits of texts in UTF-16 codes.
+const (
+	MaxMessageLength = 4096
+	MaxCaptionLength = 1024
+)
+
+// ErrSplitMarkdown is returned by SendLongMessage and SplitCaption for texts in
+// legacy ModeMarkdown, which cannot be parsed reliably. Use ModeMarkdownV2
+// instead.
+var ErrSplitMarkdown = errors.New("telegram: cannot split legacy Markdown")
+
+// TextChunk is a part of a split text.
+type TextChunk struct {
+	Text     string
+	Entities []*MessageEntity
+}
+
+// separators are boundaries text is split at in order of preference.
+var separators = [][]uint16{
+	{'\n', '\n'},
+	{'\n'},
+	{' '},
+}
+
+// SplitText splits text with entities into chunks of at most limit UTF-16
+// codes, e.g. MaxMessageLength. Text is split at paragraphs, then lines, then
+// words, and only then at any position. Boundaries inside entities are avoided;
+// an entity cut anyway is continued in the next chunk. MaxMessageLength is used
+// if limit is not positive.
+func SplitText(text string, entities []*MessageEntity, limit int) []*TextChunk {
+	if limit <= 0 {
+		limit = MaxMessageLength
+	}
+	units := utf16.Encode([]rune(text))
+	var chunks []*TextChunk
+	for start := 0; start < len(units); {
+		end, next := len(units), len(units)
+		if len(units)-start > limit {
+			end, next = cutText(units, entities, start, start+limit)
+		}
+		chunks = append(chunks, textChunk(units, entities, start, end))
+		start = next
+	}
+	return chunks
+}
+
+// textChunk returns the chunk of text units in [start, end).
+func textChunk(units []uint16, entities []*MessageEntity, start, end int) *TextChunk {
+	return &TextChunk{
+		Text:     string(utf16.Decode(units[start:end])),
+		Entities: clipEntities(entities, start, end),
+	}
+}
+
+// format returns the chunk text formatted in mode. ModeDefault returns the
+// text as is; its entities are sent separately.
+func (c *TextChunk) format(mode ParseMode) string {
+	switch mode {
+	case ModeHTML:
+		return RenderHTML(c.Text, c.Entities)
+	case ModeMarkdownV2:
+		return RenderMarkdownV2(c.Text, c.Entities)
+	}
+	return c.Text
+}
+
+// parseText returns text formatted in mode as plain text and entities, so it
+// can be split. entities are returned as is for ModeDefault.
+func parseText(text string, entities []*MessageEntity, mode ParseMode) (string, []*MessageEntity, error) {
+	switch mode {
+	case ModeHTML:
+		return ParseHTML(text)
+	case ModeMarkdownV2:
+		return ParseMarkdownV2(text)
+	case ModeMarkdown:
+		return "", nil, ErrSplitMarkdown
+	}
+	return text, entities, nil
+}
+
+// cutText returns the end of a chunk starting at start and ending before max,
+// and the start of the next chunk. Separators between chunks are dropped. A
+// separator inside an entity is used only if there are no others, e.g. when
+// the whole text is bold. Text is cut at any position if there are no
+// separators at all.
+func cutText(units []uint16, entities []*MessageEntity, start, max int) (end, next int) {
+	inside := func(pos int) bool {
+		for _, e := range entities {
+			if e.Offset < pos && pos < e.Offset+e.Length {
+				return true
+			}
+		}
+		return false
+	}
+	for _, outside := range []bool{true, false} {
+		for _, sep := range separators {
+			for pos := max; pos > start; pos-- {
+				if hasUnits(units[pos:], sep) && !(outside && inside(pos)) {
+					return pos, pos + len(sep)
+				}
+			}
+		}
+	}
+	for pos := max; pos > start; pos-- {
+		if !isLowSurrogate(units[pos]) && !inside(pos) {
+			return pos, pos
+		}
+	}
+	if isLowSurrogate(units[max]) && max-1 > start {
+		max--
+	}
+	return max, max
+}
+
+// isLowSurrogate reports whether u is the second code of a surrogate pair,
+// which must not be split.
+func isLowSurrogate(u uint16) bool {
+	return 0xdc00 <= u && u < 0xe000
+}
+
+// hasUnits reports whether units begins with prefix.
+func hasUnits(units, prefix []uint16) bool {
+	if len(units) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if units[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// clipEntities returns copies of entities clipped to [start, end) with offsets
+// relative to start.
+func clipEntities(entities []*MessageEntity, start, end int) []*MessageEntity {
+	var clipped []*MessageEntity
+	for _, e := range entities {
+		from, to := e.Offset, e.Offset+e.Length
+		if from < start {
+			from = start
+		}
+		if to > end {
+			to = end
+		}
+		if from >= to {
+			continue
+		}
+		c := *e
+		c.Offset, c.Length = from-start, to-from
+		clipped = append(clipped, &c)
+	}
+	return clipped
+}
+
+// SendLongMessage sends m split into messages of at most MaxMessageLength.
+// Texts in ModeHTML and ModeMarkdownV2 are parsed, split and formatted back in
+// the same mode; m.Entities are split with the text otherwise. So formatting is
+// kept across messages, e.g. a bold text cut by the limit stays bold in both
+// messages. Only the first message replies to m.ReplyToMessageID and only the
+// last one has m.ReplyMarkup.
+//
+// SendLongMessage returns sent messages, even if a later message fails.
+func SendLongMessage(ctx context.Context, b Bot, m *TextMessage) ([]*Message, error) {
+	// Formatted text is never shorter than the text to send.
+	if utf16Len(m.Text) <= MaxMessageLength {
+		msg, err := b.SendMessage(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		return []*Message{msg}, nil
+	}
+
+	text, entities, err := parseText(m.Text, m.Entities, m.ParseMode)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := SplitText(text, entities, MaxMessageLength)
+	var sent []*Message
+	for i, c := range chunks {
+		part := *m
+		part.Text = c.format(m.ParseMode)
+		if m.ParseMode == ModeDefault {
+			part.Entities = c.Entities
+		}
+		if i != 0 {
+			part.ReplyToMessageID = 0
+		}
+		if i != len(chunks)-1 {
+			part.ReplyMarkup = nil
+		}
+		msg, err := b.SendMessage(ctx, &part)
+		if err != nil {
+			return sent, err
+		}
+		sent = append(sent, msg)
+	}
+	return sent, nil
+}
+
+// SplitCaption splits caption formatted in mode into a caption of at most
+// MaxCaptionLength and the rest, both formatted in mode, e.g. to send the rest
+// with SendLongMessage. The caption is split like SplitText does. rest is empty
+// if the caption fits.
+func SplitCaption(caption string, mode ParseMode) (string, string, error) {
+	// Formatted text is never shorter than the caption to send.
+	if utf16Len(caption) <= MaxCaptionLength {
+		return caption, "", nil
+	}
+	text, entities, err := parseText(caption, nil, mode)
+	if err != nil {
+		return "", "", err
+	}
+	units := utf16.Encode([]rune(text))
+	if len(units) <= MaxCaptionLength {
+		return caption, "", nil
+	}
+	end, next := cutText(units, entities, 0, MaxCaptionLength)
+	first := textChunk(units, entities, 0, end)
+	rest := textChunk(units, entities, next, len(units))
+	return first.format(mode), rest.format(mode), nil
+}
@@ -0,0 +1,158 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+var SplitTextTests = []struct {
+	Name     string
+	Text     string
+	Entities []*MessageEntity
+	Limit    int
+	Chunks   []string
+}{
+	{"short", "abc def", nil, 10, []string{"abc def"}},
+	{"paragraph", "aa bb\n\ncc\ndd ee", nil, 12, []string{"aa bb", "cc\ndd ee"}},
+	{"line", "aa bb\ncc dd ee", nil, 10, []string{"aa bb", "cc dd ee"}},
+	{"word", "aa bb cc dd", nil, 7, []string{"aa bb", "cc dd"}},
+	{"hard", "abcdefgh", nil, 3, []string{"abc", "def", "gh"}},
+	{"surrogates", "👍👍👍", nil, 3, []string{"👍", "👍", "👍"}},
+	{"entity", "aa bb cc dd", []*MessageEntity{entity(EntityBold, 3, 8)}, 9, []string{"aa", "bb cc dd"}},
+	{"inside entity", "aa bb cc", []*MessageEntity{entity(EntityBold, 0, 8)}, 6, []string{"aa bb", "cc"}},
+	{"no limit", "abc", nil, 0, []string{"abc"}},
+}
+
+func TestSplitText(t *testing.T) {
+	for _, tt := range SplitTextTests {
+		chunks := SplitText(tt.Text, tt.Entities, tt.Limit)
+		var got []string
+		for _, c := range chunks {
+			got = append(got, c.Text)
+			if n := utf16Len(c.Text); tt.Limit > 0 && n > tt.Limit {
+				t.Errorf("%s: chunk %q: want at most %d codes, got %d", tt.Name, c.Text, tt.Limit, n)
+			}
+		}
+		if !stringsEqual(got, tt.Chunks) {
+			t.Errorf("%s: want %q, got %q", tt.Name, tt.Chunks, got)
+		}
+	}
+}
+
+func TestSplitTextReopensEntities(t *testing.T) {
+	text := strings.Repeat("x", 10)
+	chunks := SplitText(text, []*MessageEntity{entity(EntityBold, 0, 8)}, 5)
+	if len(chunks) != 2 {
+		t.Fatalf("chunks: want 2, got %d", len(chunks))
+	}
+	want := []*MessageEntity{entity(EntityBold, 0, 5), entity(EntityBold, 0, 3)}
+	for i, c := range chunks {
+		if len(c.Entities) != 1 || *c.Entities[0] != *want[i] {
+			t.Errorf("chunk %d: want %+v, got %+v", i, want[i], c.Entities)
+		}
+	}
+}
+
+func TestSendLongMessage(t *testing.T) {
+	b, reqc, closeBot := newTestBot(t, func(*testRequest) interface{} { return &Message{} })
+	defer closeBot()
+
+	// 50 lines of 100 codes with newlines. The whole text is bold, so it is
+	// split at the last line fitting into the first message.
+	line := strings.Repeat("a", 99) + "\n"
+	text := strings.TrimSuffix(strings.Repeat(line, 50), "\n")
+	m := &TextMessage{
+		ChatID:           1,
+		Text:             text,
+		Entities:         []*MessageEntity{entity(EntityBold, 0, utf16Len(text))},
+		ReplyToMessageID: 5,
+		ReplyMarkup:      &ReplyKeyboardRemove{RemoveKeyboard: true},
+	}
+	msgs, err := SendLongMessage(context.Background(), b, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("messages: want 2, got %d", len(msgs))
+	}
+	texts := []string{text[:3999], text[4000:]}
+	lengths := []float64{3999, 999}
+	for i := 0; i < 2; i++ {
+		req := <-reqc
+		if s := req.Body["text"]; s != texts[i] {
+			t.Errorf("message %d: want text of %d codes, got %q", i, len(texts[i]), s)
+		}
+		entities := req.Body["entities"].([]interface{})
+		e := entities[0].(map[string]interface{})
+		if len(entities) != 1 || e["type"] != EntityBold || e["offset"] != 0.0 || e["length"] != lengths[i] {
+			t.Errorf("message %d: want bold of %v codes, got %v", i, lengths[i], entities)
+		}
+		_, reply := req.Body["reply_to_message_id"]
+		_, markup := req.Body["reply_markup"]
+		if reply != (i == 0) || markup != (i == 1) {
+			t.Errorf("message %d: want reply %t and markup %t, got %t and %t", i, i == 0, i == 1, reply, markup)
+		}
+	}
+
+}
+
+func TestSendLongMessageParseMode(t *testing.T) {
+	b, reqc, closeBot := newTestBot(t, func(*testRequest) interface{} { return &Message{} })
+	defer closeBot()
+
+	// The bold text is cut by the limit, so each message has its own tags.
+	line := strings.Repeat("a", 99) + "\n"
+	text := strings.TrimSuffix(strings.Repeat(line, 50), "\n")
+	var tests = []struct {
+		Mode        ParseMode
+		Name        string
+		Open, Close string
+	}{
+		{ModeHTML, "HTML", "<b>", "</b>"},
+		{ModeMarkdownV2, "MarkdownV2", "*", "*"},
+	}
+	for _, tt := range tests {
+		m := &TextMessage{ChatID: 1, Text: tt.Open + text + tt.Close, ParseMode: tt.Mode}
+		if _, err := SendLongMessage(context.Background(), b, m); err != nil {
+			t.Fatalf("%s: %v", tt.Name, err)
+		}
+		for i, s := range []string{text[:3999], text[4000:]} {
+			req := <-reqc
+			if want := tt.Open + s + tt.Close; req.Body["text"] != want {
+				t.Errorf("%s: message %d: want bold text of %d codes, got %q", tt.Name, i, len(s), req.Body["text"])
+			}
+			if req.Body["parse_mode"] != tt.Name {
+				t.Errorf("%s: message %d: want parse mode %s, got %v", tt.Name, i, tt.Name, req.Body["parse_mode"])
+			}
+		}
+	}
+
+	m := &TextMessage{ChatID: 1, Text: "*" + text + "*", ParseMode: ModeMarkdown}
+	if _, err := SendLongMessage(context.Background(), b, m); err != ErrSplitMarkdown {
+		t.Fatalf("error: want %v, got %v", ErrSplitMarkdown, err)
+	}
+	m = &TextMessage{ChatID: 1, Text: "<b>" + text, ParseMode: ModeHTML}
+	if _, err := SendLongMessage(context.Background(), b, m); err == nil {
+		t.Fatal("error: want unclosed tag, got nil")
+	}
+}
+
+func TestSplitCaption(t *testing.T) {
+	words := strings.TrimSuffix(strings.Repeat("a ", 600), " ")
+	caption, rest, err := SplitCaption("<b>"+words+"</b>", ModeHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<b>" + words[:1023] + "</b>"; caption != want {
+		t.Errorf("caption: want %q, got %q", want, caption)
+	}
+	if want := "<b>" + words[1024:] + "</b>"; rest != want {
+		t.Errorf("rest: want %q, got %q", want, rest)
+	}
+
+	caption, rest, err = SplitCaption("<b>short</b>", ModeHTML)
+	if caption != "<b>short</b>" || rest != "" || err != nil {
+		t.Errorf("short: want (%q, \"\", nil), got (%q, %q, %v)", "<b>short</b>", caption, rest, err)
+	}
+}
@@ -0,0 +1,108 @@
+package telegram
+
+import "strings"
+
+// TextBuilder builds a text with entities, which can be sent without parse
+// mode and thus without escaping. The zero value is ready to use.
+//
+//	var b TextBuilder
+//	b.Plain("Hello, ").Bold(name).Plain("!")
+//	msg := &TextMessage{ChatID: id, Text: b.Text(), Entities: b.Entities()}
+//
+// https://core.telegram.org/bots/api#messageentity
+type TextBuilder struct {
+	text     strings.Builder
+	offset   int // in UTF-16 codes
+	entities []*MessageEntity
+}
+
+// Plain appends s without formatting.
+func (b *TextBuilder) Plain(s string) *TextBuilder {
+	b.text.WriteString(s)
+	b.offset += utf16Len(s)
+	return b
+}
+
+// Bold appends bold s.
+func (b *TextBuilder) Bold(s string) *TextBuilder {
+	return b.append(EntityBold, s, nil)
+}
+
+// Italic appends italic s.
+func (b *TextBuilder) Italic(s string) *TextBuilder {
+	return b.append(EntityItalic, s, nil)
+}
+
+// Underline appends underlined s.
+func (b *TextBuilder) Underline(s string) *TextBuilder {
+	return b.append(EntityUnderline, s, nil)
+}
+
+// Strikethrough appends strikethrough s.
+func (b *TextBuilder) Strikethrough(s string) *TextBuilder {
+	return b.append(EntityStrikethrough, s, nil)
+}
+
+// Spoiler appends s hidden as a spoiler.
+func (b *TextBuilder) Spoiler(s string) *TextBuilder {
+	return b.append(EntitySpoiler, s, nil)
+}
+
+// Code appends s as inline monowidth code.
+func (b *TextBuilder) Code(s string) *TextBuilder {
+	return b.append(EntityCode, s, nil)
+}
+
+// Pre appends s as a monowidth block of code in lang. lang may be empty.
+func (b *TextBuilder) Pre(s, lang string) *TextBuilder {
+	return b.append(EntityPre, s, func(e *MessageEntity) {
+		if lang != "" {
+			e.Language = &lang
+		}
+	})
+}
+
+// Link appends s as a link to url.
+func (b *TextBuilder) Link(s, url string) *TextBuilder {
+	return b.append(EntityTextLink, s, func(e *MessageEntity) {
+		e.URL = &url
+	})
+}
+
+// Mention appends s as a mention of u, which works for users without
+// usernames.
+func (b *TextBuilder) Mention(s string, u *User) *TextBuilder {
+	return b.append(EntityTextMention, s, func(e *MessageEntity) {
+		e.User = u
+	})
+}
+
+// append appends s with an entity of typ. Empty s is skipped as Telegram
+// rejects empty entities.
+func (b *TextBuilder) append(typ, s string, init func(*MessageEntity)) *TextBuilder {
+	n := utf16Len(s)
+	if n == 0 {
+		return b
+	}
+	e := &MessageEntity{Type: typ, Offset: b.offset, Length: n}
+	if init != nil {
+		init(e)
+	}
+	b.entities = append(b.entities, e)
+	return b.Plain(s)
+}
+
+// Text returns the built text.
+func (b *TextBuilder) Text() string {
+	return b.text.String()
+}
+
+// Entities returns entities of the built text.
+func (b *TextBuilder) Entities() []*MessageEntity {
+	return b.entities
+}
+
+// Len returns the length of the built text in UTF-16 codes.
+func (b *TextBuilder) Len() int {
+	return b.offset
+}
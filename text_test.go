@@ -0,0 +1,43 @@
+package telegram
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTextBuilder(t *testing.T) {
+	var b TextBuilder
+	b.Plain("Hi, ").
+		Mention("Иван", &User{ID: 1}).
+		Plain("! 👍 ").
+		Bold("bold").
+		Italic("").
+		Plain(" ").
+		Link("link", "https://example.com").
+		Plain("\n").
+		Pre("x := 1", "go")
+
+	if want := "Hi, Иван! 👍 bold link\nx := 1"; b.Text() != want {
+		t.Errorf("text: want %q, got %q", want, b.Text())
+	}
+	if want := 29; b.Len() != want {
+		t.Errorf("len: want %d, got %d", want, b.Len())
+	}
+	got, _ := json.Marshal(b.Entities())
+	want := `[` +
+		`{"type":"text_mention","offset":4,"length":4,"user":{"id":1,"first_name":"","last_name":null,"username":null,"language_code":null}},` +
+		`{"type":"bold","offset":13,"length":4},` +
+		`{"type":"text_link","offset":18,"length":4,"url":"https://example.com"},` +
+		`{"type":"pre","offset":23,"length":6,"language":"go"}]`
+	if string(got) != want {
+		t.Errorf("entities: want %s, got %s", want, got)
+	}
+	for _, e := range b.Entities() {
+		if e.Type != "bold" {
+			continue
+		}
+		if s := utf16Slice(b.Text(), e.Offset, e.Offset+e.Length); s != "bold" {
+			t.Errorf("bold: want %q, got %q", "bold", s)
+		}
+	}
+}
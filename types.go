@@ -1,10 +1,10 @@
 package telegram
 
 import (
+	"context"
 	"encoding/json"
-	"io"
-	"net/url"
-	"strconv"
+	"errors"
+	"unicode/utf16"
 )
 
 // Getting updates
@@ -22,17 +22,122 @@ type Update struct {
 	CallbackQuery *CallbackQuery `json:"callback_query"`
 	// ShippingQuery
 	// PreCheckoutQuery
+
+	ctx context.Context
+}
+
+// Context returns the context of handling u. It is set by RunContext of runners
+// or WithContext and is context.Background() by default.
+func (u *Update) Context() context.Context {
+	if u == nil || u.ctx == nil {
+		return context.Background()
+	}
+	return u.ctx
+}
+
+// WithContext returns a shallow copy of u with ctx. Middleware uses it to pass
+// values to handlers.
+func (u *Update) WithContext(ctx context.Context) *Update {
+	c := *u
+	c.ctx = ctx
+	return &c
+}
+
+// Type returns the type of u. It is an empty string for unknown updates.
+func (u *Update) Type() UpdateType {
+	switch {
+	case u.Message != nil:
+		return UpdateMessage
+	case u.EditedMessage != nil:
+		return UpdateEditedMessage
+	case u.ChannelPost != nil:
+		return UpdateChannelPost
+	case u.EditedChannelPost != nil:
+		return UpdateEditedChannelPost
+	case u.CallbackQuery != nil:
+		return UpdateCallbackQuery
+	}
+	return ""
+}
+
+// message returns a message of any kind from u. It is nil if u holds no
+// message.
+func (u *Update) message() *Message {
+	switch {
+	case u.Message != nil:
+		return u.Message
+	case u.EditedMessage != nil:
+		return u.EditedMessage
+	case u.ChannelPost != nil:
+		return u.ChannelPost
+	case u.EditedChannelPost != nil:
+		return u.EditedChannelPost
+	case u.CallbackQuery != nil:
+		return u.CallbackQuery.Message
+	}
+	return nil
+}
+
+// from returns a sender of u. It is nil for channel posts.
+func (u *Update) from() *User {
+	if u.CallbackQuery != nil {
+		return &u.CallbackQuery.From
+	}
+	if m := u.message(); m != nil {
+		return m.From
+	}
+	return nil
+}
+
+// UpdateType is a kind of update, i.e. the name of the optional field set in
+// Update. It is used to specify which updates the bot receives.
+type UpdateType string
+
+// Update types.
+const (
+	UpdateMessage            UpdateType = "message"
+	UpdateEditedMessage      UpdateType = "edited_message"
+	UpdateChannelPost        UpdateType = "channel_post"
+	UpdateEditedChannelPost  UpdateType = "edited_channel_post"
+	UpdateInlineQuery        UpdateType = "inline_query"
+	UpdateChosenInlineResult UpdateType = "chosen_inline_result"
+	UpdateCallbackQuery      UpdateType = "callback_query"
+	UpdateShippingQuery      UpdateType = "shipping_query"
+	UpdatePreCheckoutQuery   UpdateType = "pre_checkout_query"
+)
+
+// https://core.telegram.org/bots/api#setwebhook
+type Webhook struct {
+	URL string `json:"url"`
+	// Certificate
+	MaxConnections int `json:"max_connections,omitempty"`
+	// AllowedUpdates is nil when the previous setting must be used. Empty
+	// AllowedUpdates means all types.
+	AllowedUpdates []UpdateType `json:"allowed_updates"`
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (w Webhook) MarshalJSON() ([]byte, error) {
+	type webhook Webhook
+	v := struct {
+		webhook
+		AllowedUpdates *[]UpdateType `json:"allowed_updates,omitempty"`
+	}{webhook: webhook(w)}
+	if w.AllowedUpdates != nil {
+		v.AllowedUpdates = &w.AllowedUpdates
+	}
+	return json.Marshal(v)
 }
 
 // https://core.telegram.org/bots/api#webhookinfo
 type WebhookInfo struct {
-	URL                  string   `json:"url"`
-	HasCustomCertificate bool     `json:"has_custom_certificate"`
-	PendingUpdateCount   int      `json:"pending_update_count"`
-	LastErrorDate        int      `json:"last_error_date"`
-	LastErrorMessage     string   `json:"last_error_message"`
-	MaxConnections       int      `json:"max_connections"`
-	AllowedUpdates       []string `json:"allowed_updates"`
+	URL                  string       `json:"url"`
+	HasCustomCertificate bool         `json:"has_custom_certificate"`
+	PendingUpdateCount   int          `json:"pending_update_count"`
+	LastErrorDate        int          `json:"last_error_date"`
+	LastErrorMessage     string       `json:"last_error_message"`
+	MaxConnections       int          `json:"max_connections"`
+	AllowedUpdates       []UpdateType `json:"allowed_updates"`
 }
 
 // Available types
@@ -82,45 +187,199 @@ type Message struct {
 	Audio           *Audio           `json:"audio"`
 	Document        *Document        `json:"document"`
 	// Game
-	Photo                 []*PhotoSize `json:"photo"`
-	Sticker               *Sticker     `json:"sticker"`
-	Video                 *Video       `json:"video"`
-	Voice                 *Voice       `json:"voice"`
-	VideoNote             *VideoNote   `json:"video_note"`
-	NewChatMembers        []*User      `json:"new_chat_members"`
-	Caption               *string      `json:"caption"`
-	Contact               *Contact     `json:"contact"`
-	Location              *Location    `json:"location"`
-	Venue                 *Venue       `json:"venue"`
-	NewChatMember         *User        `json:"new_chat_member"`
-	LeftChatMember        *User        `json:"left_chat_member"`
-	NewChatTitle          *string      `json:"new_chat_title"`
-	NewChatPhoto          []*PhotoSize `json:"new_chat_photo"`
-	DeleteChatPhoto       *bool        `json:"delete_chat_photo"`
-	GroupChatCreated      *bool        `json:"group_chat_created"`
-	SupergroupChatCreated *bool        `json:"supergroup_chat_created"`
-	ChannelChatCreated    *bool        `json:"channel_chat_created"`
-	MigrateToChatID       *int64       `json:"migrate_to_chat_id"`
-	MigrateFromChatID     *int64       `json:"migrate_from_chat_id"`
-	PinnedMessage         *Message     `json:"pinned_message"`
+	Photo                 []*PhotoSize     `json:"photo"`
+	Sticker               *Sticker         `json:"sticker"`
+	Video                 *Video           `json:"video"`
+	Voice                 *Voice           `json:"voice"`
+	VideoNote             *VideoNote       `json:"video_note"`
+	NewChatMembers        []*User          `json:"new_chat_members"`
+	Caption               *string          `json:"caption"`
+	CaptionEntities       []*MessageEntity `json:"caption_entities"`
+	Contact               *Contact         `json:"contact"`
+	Location              *Location        `json:"location"`
+	Venue                 *Venue           `json:"venue"`
+	NewChatMember         *User            `json:"new_chat_member"`
+	LeftChatMember        *User            `json:"left_chat_member"`
+	NewChatTitle          *string          `json:"new_chat_title"`
+	NewChatPhoto          []*PhotoSize     `json:"new_chat_photo"`
+	DeleteChatPhoto       *bool            `json:"delete_chat_photo"`
+	GroupChatCreated      *bool            `json:"group_chat_created"`
+	SupergroupChatCreated *bool            `json:"supergroup_chat_created"`
+	ChannelChatCreated    *bool            `json:"channel_chat_created"`
+	MigrateToChatID       *int64           `json:"migrate_to_chat_id"`
+	MigrateFromChatID     *int64           `json:"migrate_from_chat_id"`
+	PinnedMessage         *Message         `json:"pinned_message"`
 	// Invoice
 	// SuccessfulPayment
+	ReplyMarkup *AnyMarkup `json:"reply_markup"`
+}
+
+// ContentType is a kind of message content.
+type ContentType string
+
+// Content types.
+const (
+	ContentText           ContentType = "text"
+	ContentAudio          ContentType = "audio"
+	ContentDocument       ContentType = "document"
+	ContentPhoto          ContentType = "photo"
+	ContentSticker        ContentType = "sticker"
+	ContentVideo          ContentType = "video"
+	ContentVoice          ContentType = "voice"
+	ContentVideoNote      ContentType = "video_note"
+	ContentContact        ContentType = "contact"
+	ContentLocation       ContentType = "location"
+	ContentVenue          ContentType = "venue"
+	ContentNewChatMembers ContentType = "new_chat_members"
+	ContentLeftChatMember ContentType = "left_chat_member"
+	ContentNewChatTitle   ContentType = "new_chat_title"
+	ContentNewChatPhoto   ContentType = "new_chat_photo"
+	ContentPinnedMessage  ContentType = "pinned_message"
+)
+
+// ContentType returns the kind of m content. It is an empty string for unknown
+// content.
+func (m *Message) ContentType() ContentType {
+	switch {
+	case m.Text != nil:
+		return ContentText
+	case m.Audio != nil:
+		return ContentAudio
+	case m.Document != nil:
+		return ContentDocument
+	case m.Photo != nil:
+		return ContentPhoto
+	case m.Sticker != nil:
+		return ContentSticker
+	case m.Video != nil:
+		return ContentVideo
+	case m.Voice != nil:
+		return ContentVoice
+	case m.VideoNote != nil:
+		return ContentVideoNote
+	case m.Contact != nil:
+		return ContentContact
+	// A venue message has location too.
+	case m.Venue != nil:
+		return ContentVenue
+	case m.Location != nil:
+		return ContentLocation
+	case m.NewChatMembers != nil:
+		return ContentNewChatMembers
+	case m.LeftChatMember != nil:
+		return ContentLeftChatMember
+	case m.NewChatTitle != nil:
+		return ContentNewChatTitle
+	case m.NewChatPhoto != nil:
+		return ContentNewChatPhoto
+	case m.PinnedMessage != nil:
+		return ContentPinnedMessage
+	}
+	return ""
 }
 
 // https://core.telegram.org/bots/api#messageentity
 type MessageEntity struct {
-	Type   string  `json:"type"`
-	Offset int     `json:"offset"`
-	Length int     `json:"length"`
-	URL    *string `json:"url"`
-	User   *User   `json:"user"`
+	Type     string  `json:"type"`
+	Offset   int     `json:"offset"`
+	Length   int     `json:"length"`
+	URL      *string `json:"url,omitempty"`
+	User     *User   `json:"user,omitempty"`
+	Language *string `json:"language,omitempty"`
+
+	CustomEmojiID *string `json:"custom_emoji_id,omitempty"`
 }
 
-func (e *MessageEntity) IsMention() bool    { return e.Type == "mention" }
-func (e *MessageEntity) IsHashtag() bool    { return e.Type == "hastag" }
-func (e *MessageEntity) IsBotCommand() bool { return e.Type == "bot_command" }
-func (e *MessageEntity) IsURL() bool        { return e.Type == "url" }
-func (e *MessageEntity) IsEmail() bool      { return e.Type == "email" }
+// Message entity types.
+const (
+	EntityMention              = "mention"
+	EntityHashtag              = "hashtag"
+	EntityCashtag              = "cashtag"
+	EntityBotCommand           = "bot_command"
+	EntityURL                  = "url"
+	EntityEmail                = "email"
+	EntityPhoneNumber          = "phone_number"
+	EntityBold                 = "bold"
+	EntityItalic               = "italic"
+	EntityUnderline            = "underline"
+	EntityStrikethrough        = "strikethrough"
+	EntitySpoiler              = "spoiler"
+	EntityBlockquote           = "blockquote"
+	EntityExpandableBlockquote = "expandable_blockquote"
+	EntityCode                 = "code"
+	EntityPre                  = "pre"
+	EntityTextLink             = "text_link"
+	EntityTextMention          = "text_mention"
+	EntityCustomEmoji          = "custom_emoji"
+)
+
+func (e *MessageEntity) IsMention() bool       { return e.Type == EntityMention }
+func (e *MessageEntity) IsHashtag() bool       { return e.Type == EntityHashtag }
+func (e *MessageEntity) IsCashtag() bool       { return e.Type == EntityCashtag }
+func (e *MessageEntity) IsBotCommand() bool    { return e.Type == EntityBotCommand }
+func (e *MessageEntity) IsURL() bool           { return e.Type == EntityURL }
+func (e *MessageEntity) IsEmail() bool         { return e.Type == EntityEmail }
+func (e *MessageEntity) IsPhoneNumber() bool   { return e.Type == EntityPhoneNumber }
+func (e *MessageEntity) IsBold() bool          { return e.Type == EntityBold }
+func (e *MessageEntity) IsItalic() bool        { return e.Type == EntityItalic }
+func (e *MessageEntity) IsUnderline() bool     { return e.Type == EntityUnderline }
+func (e *MessageEntity) IsStrikethrough() bool { return e.Type == EntityStrikethrough }
+func (e *MessageEntity) IsSpoiler() bool       { return e.Type == EntitySpoiler }
+func (e *MessageEntity) IsCode() bool          { return e.Type == EntityCode }
+func (e *MessageEntity) IsPre() bool           { return e.Type == EntityPre }
+func (e *MessageEntity) IsTextLink() bool      { return e.Type == EntityTextLink }
+func (e *MessageEntity) IsTextMention() bool   { return e.Type == EntityTextMention }
+func (e *MessageEntity) IsCustomEmoji() bool   { return e.Type == EntityCustomEmoji }
+
+func (e *MessageEntity) IsBlockquote() bool {
+	return e.Type == EntityBlockquote || e.Type == EntityExpandableBlockquote
+}
+
+// Mentions returns @usernames mentioned in m text and caption.
+func (m *Message) Mentions() []string { return m.entityTexts(EntityMention) }
+
+// Hashtags returns #hashtags of m text and caption.
+func (m *Message) Hashtags() []string { return m.entityTexts(EntityHashtag) }
+
+// Cashtags returns $USD-like cashtags of m text and caption.
+func (m *Message) Cashtags() []string { return m.entityTexts(EntityCashtag) }
+
+// URLs returns URLs found in m text and caption. Links hidden behind text,
+// i.e. text_link entities, are not included.
+func (m *Message) URLs() []string { return m.entityTexts(EntityURL) }
+
+// Emails returns email addresses found in m text and caption.
+func (m *Message) Emails() []string { return m.entityTexts(EntityEmail) }
+
+// PhoneNumbers returns phone numbers found in m text and caption.
+func (m *Message) PhoneNumbers() []string { return m.entityTexts(EntityPhoneNumber) }
+
+// entityTexts returns texts of entities of typ in m text and then caption.
+func (m *Message) entityTexts(typ string) []string {
+	var texts []string
+	extract := func(text *string, entities []*MessageEntity) {
+		if text == nil {
+			return
+		}
+		var units []uint16
+		for _, e := range entities {
+			if e.Type != typ {
+				continue
+			}
+			if units == nil {
+				units = utf16.Encode([]rune(*text))
+			}
+			from, to := e.Offset, e.Offset+e.Length
+			if from < 0 || to > len(units) || from > to {
+				continue
+			}
+			texts = append(texts, string(utf16.Decode(units[from:to])))
+		}
+	}
+	extract(m.Text, m.Entities)
+	extract(m.Caption, m.CaptionEntities)
+	return texts
+}
 
 // https://core.telegram.org/bots/api#photosize
 type PhotoSize struct {
@@ -240,11 +499,12 @@ type InlineKeyboardMarkup struct {
 
 // https://core.telegram.org/bots/api#inlinekeyboardbutton
 type InlineKeyboardButton struct {
-	Text                         string `json:"text"`
-	URL                          string `json:"url,omitempty"`
-	CallbackData                 string `json:"callback_data,omitempty"`
-	SwitchInlineQuery            string `json:"switch_inline_query,omitempty"`
-	SwitchInlineQueryCurrentChat string `json:"switch_inline_query_current_chat,omitempty"`
+	Text         string `json:"text"`
+	URL          string `json:"url,omitempty"`
+	CallbackData string `json:"callback_data,omitempty"`
+	// Switch queries may be empty, which inserts only the bot username.
+	SwitchInlineQuery            *string `json:"switch_inline_query,omitempty"`
+	SwitchInlineQueryCurrentChat *string `json:"switch_inline_query_current_chat,omitempty"`
 	// CallbackGame
 	// Pay
 }
@@ -299,17 +559,12 @@ type ResponseParameters struct {
 	RetryAfter      *int   `json:"retry_after"`
 }
 
-// https://core.telegram.org/bots/api#inputfile
-type InputFile interface {
-	io.Reader
-	Name() string
-}
-
 // Parse modes.
 const (
-	ModeDefault  ParseMode = 0
-	ModeMarkdown           = 1
-	ModeHTML               = 2
+	ModeDefault    ParseMode = 0
+	ModeMarkdown             = 1 // legacy, see ModeMarkdownV2
+	ModeHTML                 = 2
+	ModeMarkdownV2           = 3
 )
 
 type ParseMode int
@@ -326,24 +581,98 @@ func (m ParseMode) MarshalJSON() (b []byte, err error) {
 		b = []byte(`"Markdown"`)
 	case ModeHTML:
 		b = []byte(`"HTML"`)
+	case ModeMarkdownV2:
+		b = []byte(`"MarkdownV2"`)
 	}
 	return
 }
 
 // https://core.telegram.org/bots/api#sendmessage
 type TextMessage struct {
-	ChatID                int64     `json:"chat_id"`
-	Text                  string    `json:"text"`
-	ParseMode             ParseMode `json:"parse_mode,omitempty"`
-	DisableWebPagePreview bool      `json:"disable_web_page_preview,omitempty"`
-	DisableNotification   bool      `json:"disable_notification,omitempty"`
-	ReplyToMessageID      int       `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup           Markup    `json:"reply_markup,omitempty"`
+	ChatID                int64            `json:"chat_id"`
+	Text                  string           `json:"text"`
+	ParseMode             ParseMode        `json:"parse_mode,omitempty"`
+	Entities              []*MessageEntity `json:"entities,omitempty"`
+	DisableWebPagePreview bool             `json:"disable_web_page_preview,omitempty"`
+	DisableNotification   bool             `json:"disable_notification,omitempty"`
+	ReplyToMessageID      int              `json:"reply_to_message_id,omitempty"`
+	ReplyMarkup           Markup           `json:"reply_markup,omitempty"`
+}
+
+// Markup is one of ReplyKeyboardMarkup, ReplyKeyboardRemove,
+// InlineKeyboardMarkup and ForceReply.
+type Markup interface {
+	markup()
 }
 
-type Markup interface {
-	json.Marshaler
-	json.Unmarshaler
+// UnmarshalMarkup decodes any kind of Markup from JSON object b.
+func UnmarshalMarkup(b []byte) (Markup, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, err
+	}
+	var m Markup
+	switch {
+	case keys["inline_keyboard"] != nil:
+		m = new(InlineKeyboardMarkup)
+	case keys["keyboard"] != nil:
+		m = new(ReplyKeyboardMarkup)
+	case keys["remove_keyboard"] != nil:
+		m = new(ReplyKeyboardRemove)
+	case keys["force_reply"] != nil:
+		m = new(ForceReply)
+	default:
+		return nil, ErrUnknownMarkup
+	}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ErrUnknownMarkup is returned by UnmarshalMarkup for unknown objects.
+var ErrUnknownMarkup = errors.New("telegram: unknown reply markup")
+
+// AnyMarkup holds Markup of any kind, e.g. of a received message. Markup is nil
+// for markup of unknown kinds, which is kept in Raw instead, so new kinds added
+// to API do not break decoding of updates.
+type AnyMarkup struct {
+	Markup
+	Raw json.RawMessage
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (m AnyMarkup) MarshalJSON() ([]byte, error) {
+	if m.Markup == nil && m.Raw != nil {
+		return m.Raw, nil
+	}
+	return json.Marshal(m.Markup)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (m *AnyMarkup) UnmarshalJSON(b []byte) error {
+	v, err := UnmarshalMarkup(b)
+	switch err {
+	case nil:
+		m.Markup, m.Raw = v, nil
+	case ErrUnknownMarkup:
+		m.Markup, m.Raw = nil, append(json.RawMessage(nil), b...)
+	default:
+		return err
+	}
+	return nil
+}
+
+// marshalMarkup returns m as a multipart form value.
+func marshalMarkup(m Markup) string {
+	if m == nil {
+		return ""
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	return string(b)
 }
 
 var _ Markup = (*ReplyKeyboardMarkup)(nil)
@@ -361,104 +690,49 @@ type ForwardedMessage struct {
 
 // https://core.telegram.org/bots/api#sendphoto
 type PhotoMessage struct {
-	ChatID              int64     `json:"chat_id"`
-	Photo               InputFile `json:"-"`
-	PhotoID             string    `json:"photo,omitempty"`
-	Caption             string    `json:"caption,omitempty"`
-	DisableNotification bool      `json:"disable_notification,omitempty"`
-	ReplyToMessageID    int       `json:"reply_to_message_id,omitempty"`
+	ChatID              int64      `json:"chat_id"`
+	Photo               *InputFile `json:"photo"`
+	Caption             string     `json:"caption,omitempty"`
+	DisableNotification bool       `json:"disable_notification,omitempty"`
+	ReplyToMessageID    int        `json:"reply_to_message_id,omitempty"`
 }
 
 // Multipart implements Multiparter interface.
 func (m *PhotoMessage) Multipart() *Multipart {
-	if m.Photo == nil {
-		return nil
-	}
-	return &Multipart{
-		Files: map[string]InputFile{"photo": m.Photo},
-		// TODO: Do not include optional fields.
-		Form: url.Values{
-			"chat_id":              {strconv.FormatInt(m.ChatID, 10)},
-			"caption":              {m.Caption},
-			"disable_notification": {strconv.FormatBool(m.DisableNotification)},
-			"reply_to_message_id":  {strconv.FormatInt(int64(m.ReplyToMessageID), 10)},
-		},
-	}
+	return newMultipart(m, map[string]*InputFile{"photo": m.Photo})
 }
 
 // https://core.telegram.org/bots/api#sendaudio
 type AudioMessage struct {
-	ChatID              int64     `json:"chat_id"`
-	Audio               InputFile `json:"-"`
-	AudioID             string    `json:"audio"`
-	Caption             string    `json:"caption,omitempty"`
-	Duration            int       `json:"duration,omitempty"`
-	Performer           string    `json:"performer,omitempty"`
-	Title               string    `json:"title,omitempty"`
-	DisableNotification bool      `json:"disable_notification,omitempty"`
-	ReplyToMessageID    int       `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup         Markup    `json:"reply_markup,omitempty"`
+	ChatID              int64      `json:"chat_id"`
+	Audio               *InputFile `json:"audio"`
+	Caption             string     `json:"caption,omitempty"`
+	Duration            int        `json:"duration,omitempty"`
+	Performer           string     `json:"performer,omitempty"`
+	Title               string     `json:"title,omitempty"`
+	DisableNotification bool       `json:"disable_notification,omitempty"`
+	ReplyToMessageID    int        `json:"reply_to_message_id,omitempty"`
+	ReplyMarkup         Markup     `json:"reply_markup,omitempty"`
 }
 
 // Multipart implements Multiparter interface.
 func (m *AudioMessage) Multipart() *Multipart {
-	if m.Audio == nil {
-		return nil
-	}
-	var markup string
-	if m.ReplyMarkup != nil {
-		if b, err := m.ReplyMarkup.MarshalJSON(); err == nil {
-			markup = string(b)
-		}
-	}
-	return &Multipart{
-		Files: map[string]InputFile{"audio": m.Audio},
-		// TODO: Do not include optional fields.
-		Form: url.Values{
-			"chat_id":              {strconv.FormatInt(m.ChatID, 10)},
-			"caption":              {m.Caption},
-			"duration":             {strconv.FormatInt(int64(m.Duration), 10)},
-			"performer":            {m.Performer},
-			"title":                {m.Title},
-			"disable_notification": {strconv.FormatBool(m.DisableNotification)},
-			"reply_to_message_id":  {strconv.FormatInt(int64(m.ReplyToMessageID), 10)},
-			"reply_markup":         {markup},
-		},
-	}
+	return newMultipart(m, map[string]*InputFile{"audio": m.Audio})
 }
 
 // https://core.telegram.org/bots/api#senddocument
 type DocumentMessage struct {
-	ChatID              int64     `json:"chat_id"`
-	Document            InputFile `json:"document"`
-	Caption             string    `json:"caption,omitempty"`
-	DisableNotification bool      `json:"disable_notification,omitempty"`
-	ReplyToMessageID    int       `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup         Markup    `json:"reply_markup,omitempty"`
+	ChatID              int64      `json:"chat_id"`
+	Document            *InputFile `json:"document"`
+	Caption             string     `json:"caption,omitempty"`
+	DisableNotification bool       `json:"disable_notification,omitempty"`
+	ReplyToMessageID    int        `json:"reply_to_message_id,omitempty"`
+	ReplyMarkup         Markup     `json:"reply_markup,omitempty"`
 }
 
 // Multipart implements Multiparter interface.
 func (m *DocumentMessage) Multipart() *Multipart {
-	if m.Document == nil {
-		return nil
-	}
-	var markup string
-	if m.ReplyMarkup != nil {
-		if b, err := m.ReplyMarkup.MarshalJSON(); err == nil {
-			markup = string(b)
-		}
-	}
-	return &Multipart{
-		Files: map[string]InputFile{"document": m.Document},
-		// TODO: Do not include optional fields.
-		Form: url.Values{
-			"chat_id":              {strconv.FormatInt(m.ChatID, 10)},
-			"caption":              {m.Caption},
-			"disable_notification": {strconv.FormatBool(m.DisableNotification)},
-			"reply_to_message_id":  {strconv.FormatInt(int64(m.ReplyToMessageID), 10)},
-			"reply_markup":         {markup},
-		},
-	}
+	return newMultipart(m, map[string]*InputFile{"document": m.Document})
 }
 
 // VideoMessage
@@ -511,6 +785,17 @@ type CallbackQueryAnswer struct {
 	CacheTime       int    `json:"cache_time,omitempty"`
 }
 
+// https://core.telegram.org/bots/api#botcommand
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// https://core.telegram.org/bots/api#setmycommands
+type MyCommands struct {
+	Commands []*BotCommand `json:"commands"`
+}
+
 // Updating messages
 // https://core.telegram.org/bots/api#updating-messages
 
@@ -521,6 +806,7 @@ type MessageText struct {
 	InlineMessageID       int                   `json:"inline_message_id,omitempty"`
 	Text                  string                `json:"text"`
 	ParseMode             ParseMode             `json:"parse_mode"`
+	Entities              []*MessageEntity      `json:"entities,omitempty"`
 	DisableWebPagePreview bool                  `json:"disable_web_page_preview"`
 	ReplyMarkup           *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
 }
@@ -581,14 +867,20 @@ type MaskPosition struct {
 
 // https://core.telegram.org/bots/api#sendsticker
 type StickerMessage struct {
-	ChatID              int64     `json:"chat_id"`
-	Sticker             InputFile `json:"-"`
-	StickerID           string    `json:"sticker"`
-	DisableNotification bool      `json:"disalbe_notification,omitempty"`
-	ReplyToMessageID    int       `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup         Markup    `json:"reply_markup,omitempty"`
+	ChatID              int64      `json:"chat_id"`
+	Sticker             *InputFile `json:"sticker"`
+	DisableNotification bool       `json:"disable_notification,omitempty"`
+	ReplyToMessageID    int        `json:"reply_to_message_id,omitempty"`
+	ReplyMarkup         Markup     `json:"reply_markup,omitempty"`
 }
 
+// Multipart implements Multiparter interface.
+func (m *StickerMessage) Multipart() *Multipart {
+	return newMultipart(m, map[string]*InputFile{"sticker": m.Sticker})
+}
+
+var _ Multiparter = (*StickerMessage)(nil)
+
 // Inline mode
 // https://core.telegram.org/bots/api#inline-mode
 // TODO: Add types and methods.
@@ -3,6 +3,7 @@ package telegram
 import (
 	"bytes"
 	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -10,24 +11,77 @@ func TestReplyKeyboardMarkup_MarshalJSON(t *testing.T) {
 	m := &ReplyKeyboardMarkup{
 		Keyboard: [][]*KeyboardButton{{{Text: "test"}}},
 	}
-	b, err := json.Marshal(m)
+	b, err := json.Marshal(&TextMessage{Text: "a", ReplyMarkup: m})
 	if err != nil {
 		t.Fatal(err)
 	}
-	want := `"{\"keyboard\":[[{\"text\":\"test\"}]]}"`
+	want := `{"chat_id":0,"text":"a","reply_markup":{"keyboard":[[{"text":"test"}]]}}`
 	if s := string(b); s != want {
 		t.Fatalf("json: want %q, got %q", want, s)
 	}
 }
 
-func TestReplyKeyboardMarkup_UnmarshalJSON(t *testing.T) {
-	var m ReplyKeyboardMarkup
-	s := `"{\"keyboard\":[[{\"text\":\"test\"}]]}"`
+var UnmarshalMarkupTests = []struct {
+	JSON string
+	Want Markup
+}{
+	{`{"inline_keyboard":[[{"text":"a","callback_data":"b"}]]}`, &InlineKeyboardMarkup{}},
+	{`{"keyboard":[[{"text":"test"}]]}`, &ReplyKeyboardMarkup{}},
+	{`{"remove_keyboard":true}`, &ReplyKeyboardRemove{}},
+	{`{"force_reply":true,"selective":false}`, &ForceReply{}},
+}
+
+func TestUnmarshalMarkup(t *testing.T) {
+	for _, tt := range UnmarshalMarkupTests {
+		m, err := UnmarshalMarkup([]byte(tt.JSON))
+		if err != nil {
+			t.Errorf("%s: %v", tt.JSON, err)
+			continue
+		}
+		if reflect.TypeOf(m) != reflect.TypeOf(tt.Want) {
+			t.Errorf("%s: want %T, got %T", tt.JSON, tt.Want, m)
+		}
+		if b, _ := json.Marshal(m); string(b) != tt.JSON {
+			t.Errorf("%s: marshalled back to %s", tt.JSON, b)
+		}
+	}
+	if _, err := UnmarshalMarkup([]byte(`{}`)); err != ErrUnknownMarkup {
+		t.Errorf("error: want %v, got %v", ErrUnknownMarkup, err)
+	}
+}
+
+func TestMessageUnknownMarkup(t *testing.T) {
+	var m Message
+	if err := json.Unmarshal([]byte(`{"reply_markup":{"new_keyboard":[]}}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ReplyMarkup.Markup != nil {
+		t.Fatalf("markup: want nil, got %T", m.ReplyMarkup.Markup)
+	}
+	if b, _ := json.Marshal(m.ReplyMarkup); string(b) != `{"new_keyboard":[]}` {
+		t.Fatalf("json: want %s, got %s", `{"new_keyboard":[]}`, b)
+	}
+}
+
+func TestMessage_ReplyMarkup(t *testing.T) {
+	var m Message
+	s := `{"message_id":1,"reply_markup":{"inline_keyboard":[[{"text":"a","url":"http://x.com"}]]}}`
 	if err := json.Unmarshal([]byte(s), &m); err != nil {
 		t.Fatal(err)
 	}
-	if s := m.Keyboard[0][0].Text; s != "test" {
-		t.Fatalf("button: want %q, got %q", "test", s)
+	k, ok := m.ReplyMarkup.Markup.(*InlineKeyboardMarkup)
+	if !ok || k.InlineKeyboard[0][0].URL != "http://x.com" {
+		t.Fatalf("unexpected markup %+v", m.ReplyMarkup)
+	}
+}
+
+func TestDocumentMessage_MultipartMarkup(t *testing.T) {
+	m := &DocumentMessage{
+		Document:    FileFromBytes("a.txt", []byte("a")),
+		ReplyMarkup: &ReplyKeyboardRemove{RemoveKeyboard: true},
+	}
+	if got, want := m.Multipart().Form.Get("reply_markup"), `{"remove_keyboard":true}`; got != want {
+		t.Fatalf("reply_markup: want %s, got %s", want, got)
 	}
 }
 
@@ -39,6 +93,7 @@ var parseModeTests = []struct {
 	{"default", ModeDefault, []byte(`""`)},
 	{"markdown", ModeMarkdown, []byte(`"Markdown"`)},
 	{"html", ModeHTML, []byte(`"HTML"`)},
+	{"markdownv2", ModeMarkdownV2, []byte(`"MarkdownV2"`)},
 }
 
 func TestParseMode_MarshalJSON(t *testing.T) {
@@ -50,3 +105,50 @@ func TestParseMode_MarshalJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestMessageEntity_IsHashtag(t *testing.T) {
+	var m Message
+	if err := json.Unmarshal([]byte(`{"text":"#go","entities":[{"type":"hashtag","offset":0,"length":3}]}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Entities[0].IsHashtag() {
+		t.Error("hashtag entity is not a hashtag")
+	}
+}
+
+func TestMessage_EntityTexts(t *testing.T) {
+	m := &Message{
+		Text: ref("👍👍 @alice #go $USD 👍 #ok"),
+		Entities: []*MessageEntity{
+			{Type: EntityMention, Offset: 5, Length: 6},
+			{Type: EntityHashtag, Offset: 12, Length: 3},
+			{Type: EntityCashtag, Offset: 16, Length: 4},
+			{Type: EntityHashtag, Offset: 24, Length: 3},
+			{Type: EntityHashtag, Offset: 26, Length: 3}, // out of range
+		},
+		Caption: ref("📷 bob@example.com +1 555 010 https://go.dev"),
+		CaptionEntities: []*MessageEntity{
+			{Type: EntityEmail, Offset: 3, Length: 15},
+			{Type: EntityPhoneNumber, Offset: 19, Length: 10},
+			{Type: EntityURL, Offset: 30, Length: 14},
+			{Type: EntityHashtag, Offset: 0, Length: 2},
+		},
+	}
+	tests := []struct {
+		Name string
+		Got  []string
+		Want []string
+	}{
+		{"mentions", m.Mentions(), []string{"@alice"}},
+		{"hashtags", m.Hashtags(), []string{"#go", "#ok", "📷"}},
+		{"cashtags", m.Cashtags(), []string{"$USD"}},
+		{"emails", m.Emails(), []string{"bob@example.com"}},
+		{"phone numbers", m.PhoneNumbers(), []string{"+1 555 010"}},
+		{"urls", m.URLs(), []string{"https://go.dev"}},
+	}
+	for _, tt := range tests {
+		if !stringsEqual(tt.Got, tt.Want) {
+			t.Errorf("%s: want %q, got %q", tt.Name, tt.Want, tt.Got)
+		}
+	}
+}